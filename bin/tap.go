@@ -1,7 +1,9 @@
 package main
 
 import (
+	"fmt"
 	"os"
+	"strings"
 
 	"github.com/TuringGpt/iac-test-automations/lib"
 	"github.com/aws/aws-cdk-go/awscdk/v2"
@@ -13,16 +15,11 @@ func main() {
 
 	app := awscdk.NewApp(nil)
 
-	// Get environment suffix from context (set by CI/CD pipeline) or use 'dev' as default
-	var environmentSuffix string
-	if suffix := app.Node().TryGetContext(jsii.String("environmentSuffix")); suffix != nil {
-		if suffixStr, ok := suffix.(string); ok {
-			environmentSuffix = suffixStr
-		} else {
-			environmentSuffix = "dev"
-		}
-	} else {
-		environmentSuffix = "dev"
+	// Get environment suffix from context (set by CI/CD pipeline), ENVIRONMENT_SUFFIX or 'dev'
+	environmentSuffix, err := lib.ResolveEnvironmentSuffix(app, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "resolving environment suffix: %v\n", err)
+		os.Exit(1)
 	}
 
 	stackName := "TapStack" + environmentSuffix
@@ -55,8 +52,100 @@ func main() {
 		EnvironmentSuffix: jsii.String(environmentSuffix),
 	}
 
+	// Resource names follow [org-]app-<suffix>[-<region code>]-<component>
+	namer := &lib.DefaultNamer{
+		Organization: getEnv("ORG_PREFIX", ""),
+		Application:  getEnv("APP_NAME", "prod"),
+		Environment:  environmentSuffix,
+	}
+	if getEnv("NAME_WITH_REGION", "false") == "true" {
+		namer.Region = region
+	}
+	props.Namer = namer
+
+	// Enable the ALB HTTPS listener when a certificate is provided
+	if certificateArn := getEnv("ALB_CERTIFICATE_ARN", ""); certificateArn != "" {
+		props.CertificateArn = jsii.String(certificateArn)
+	}
+
+	// Enable the Aurora data tier (aurora-postgresql or aurora-mysql)
+	props.DatabaseEngine = lib.DatabaseEngine(getEnv("DATABASE_ENGINE", ""))
+
+	// Deploy into an existing VPC and/or encrypt with an existing KMS key
+	if vpcId := getEnv("EXISTING_VPC_ID", ""); vpcId != "" {
+		props.ExistingVpc = &lib.ExistingVpcConfig{
+			VpcId:       jsii.String(vpcId),
+			HasFlowLogs: getEnv("EXISTING_VPC_HAS_FLOW_LOGS", "false") == "true",
+		}
+	}
+	if kmsKeyArn := getEnv("KMS_KEY_ARN", ""); kmsKeyArn != "" {
+		props.ExistingKmsKeyArn = jsii.String(kmsKeyArn)
+	}
+	props.KmsKeyPerDomain = getEnv("KMS_KEY_PER_DOMAIN", "false") == "true"
+
+	// Service names live in <suffix>.internal unless overridden; a public zone is opt-in
+	if privateZoneName := getEnv("PRIVATE_ZONE_NAME", ""); privateZoneName != "" {
+		props.PrivateZoneName = jsii.String(privateZoneName)
+	}
+	if publicZoneName := getEnv("PUBLIC_ZONE_NAME", ""); publicZoneName != "" {
+		props.PublicZoneName = jsii.String(publicZoneName)
+	}
+
+	// Attach to a shared Transit Gateway for hub-and-spoke connectivity
+	if transitGatewayId := getEnv("TRANSIT_GATEWAY_ID", ""); transitGatewayId != "" {
+		props.TransitGateway = &lib.TransitGatewayConfig{
+			TransitGatewayId: jsii.String(transitGatewayId),
+			DestinationCidrs: splitList(getEnv("TRANSIT_GATEWAY_CIDRS", "")),
+			ReservedCidrs:    splitList(getEnv("RESERVED_CIDRS", "")),
+		}
+	}
+
+	// Fill sizing from the per-environment profile (config/<suffix>.json)
+	profile, err := lib.LoadProfile(getEnv("CONFIG_DIR", "config"), environmentSuffix)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "loading environment profile: %v\n", err)
+		os.Exit(1)
+	}
+	props.ApplyProfile(profile)
+
+	// Reject impossible sizing before any stack is synthesized
+	if err := props.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid stack configuration: %v\n", err)
+		os.Exit(1)
+	}
+
+	// CLOUDFRONT-scoped WAF and CloudFront certificates must live in us-east-1,
+	// so split them into an edge stack when the workload is deployed elsewhere
+	cdnDomainName := getEnv("CDN_DOMAIN_NAME", "")
+	if cdnDomainName != "" && env == nil {
+		fmt.Fprintln(os.Stderr, "CDN_DOMAIN_NAME requires CDK_DEFAULT_ACCOUNT and CDK_DEFAULT_REGION, the certificate is issued in a separate us-east-1 stack")
+		os.Exit(1)
+	}
+	if env != nil && (region != lib.EdgeRegion || cdnDomainName != "") {
+		edgeProps := &lib.EdgeStackProps{
+			StackProps: &awscdk.StackProps{
+				Env: env,
+			},
+			EnvironmentSuffix: jsii.String(environmentSuffix),
+			BlockedCidrs:      props.Cdn.BlockedCidrs,
+			Namer:             namer,
+		}
+		if cdnDomainName != "" {
+			edgeProps.CdnDomainName = jsii.String(cdnDomainName)
+		}
+		edgeStack, err := lib.NewEdgeStackE(app, jsii.String("TapEdgeStack"+environmentSuffix), edgeProps)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "creating edge stack: %v\n", err)
+			os.Exit(1)
+		}
+		props.EdgeStack = edgeStack
+	}
+
 	// Initialize the stack with proper parameters
-	lib.NewTapStack(app, jsii.String(stackName), props)
+	if _, err := lib.NewTapStackE(app, jsii.String(stackName), props); err != nil {
+		fmt.Fprintf(os.Stderr, "creating stack: %v\n", err)
+		os.Exit(1)
+	}
 
 	app.Synth(nil)
 }
@@ -68,3 +157,14 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+// splitList splits a comma-separated environment variable, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
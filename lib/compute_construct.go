@@ -0,0 +1,441 @@
+package lib
+
+import (
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsautoscaling"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsec2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awselasticloadbalancingv2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslambda"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslogs"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awss3"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsssm"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// ComputeConstructProps defines the inputs of the ComputeConstruct.
+type ComputeConstructProps struct {
+	// Namer builds resource names.
+	Namer             Namer
+	EnvironmentSuffix *string
+	Vpc               awsec2.IVpc
+	PublicSubnets     *[]awsec2.ISubnet
+	PrivateSubnets    *[]awsec2.ISubnet
+	// Security groups for each tier
+	LambdaSecurityGroup       awsec2.ISecurityGroup
+	InstanceSecurityGroup     awsec2.ISecurityGroup
+	LoadBalancerSecurityGroup awsec2.ISecurityGroup
+	BastionSecurityGroup      awsec2.ISecurityGroup
+	// InstanceConnectEndpointSecurityGroup is attached to the EC2 Instance
+	// Connect Endpoint when Compute.InstanceConnectEndpoint is set.
+	InstanceConnectEndpointSecurityGroup awsec2.ISecurityGroup
+	// Bucket is read and written by the background job Lambda.
+	Bucket awss3.IBucket
+	// SessionLogBucket receives Session Manager transcripts in BastionModeSsm.
+	SessionLogBucket awss3.IBucket
+	// KmsKeys gives the Lambda the app data key and encrypts its log group
+	// and sessions with the logs key.
+	KmsKeys KmsKeys
+	// CertificateArn enables the ALB HTTPS listener.
+	CertificateArn *string
+	Compute        ComputeConfig
+	Lambda         LambdaConfig
+	LogRetention   awslogs.RetentionDays
+	RemovalPolicy  awscdk.RemovalPolicy
+	// DualStack serves the ALB over IPv4 and IPv6.
+	DualStack bool
+}
+
+// ComputeConstruct holds the background job Lambda, the web tier Auto Scaling
+// Group behind its Application Load Balancer, and the bastion host.
+type ComputeConstruct struct {
+	constructs.Construct
+	LambdaFunction   awslambda.Function
+	AutoScalingGroup awsautoscaling.AutoScalingGroup
+	LoadBalancer     awselasticloadbalancingv2.ApplicationLoadBalancer
+	// BastionHost is nil in BastionModeNone.
+	BastionHost awsec2.BastionHostLinux
+	// SessionLogGroup and SessionPreferences are only set in BastionModeSsm.
+	SessionLogGroup    awslogs.LogGroup
+	SessionPreferences awsssm.CfnDocument
+	// InstanceConnectEndpoint is only set when Compute.InstanceConnectEndpoint is.
+	InstanceConnectEndpoint awsec2.CfnInstanceConnectEndpoint
+}
+
+// NewComputeConstruct creates the Lambda, web tier and bastion host.
+func NewComputeConstruct(scope constructs.Construct, id *string, props *ComputeConstructProps) *ComputeConstruct {
+	compute := &ComputeConstruct{
+		Construct: constructs.NewConstruct(scope, id),
+	}
+
+	compute.createLambdaFunction(props)
+	compute.createEC2Resources(props)
+	compute.createLoadBalancer(props)
+	compute.createBastionHost(props)
+	compute.createInstanceConnectEndpoint(props)
+
+	return compute
+}
+
+// createLambdaFunction creates Lambda function with proper IAM roles (least privilege)
+func (c *ComputeConstruct) createLambdaFunction(props *ComputeConstructProps) {
+	// Create IAM role for Lambda with least privilege
+	lambdaRole := awsiam.NewRole(c.Construct, jsii.String("ProdLambdaRole"), &awsiam.RoleProps{
+		RoleName:  resourceName(props.Namer, ResourceIamRole, "lambda-role"),
+		AssumedBy: awsiam.NewServicePrincipal(jsii.String("lambda.amazonaws.com"), nil),
+		ManagedPolicies: &[]awsiam.IManagedPolicy{
+			awsiam.ManagedPolicy_FromAwsManagedPolicyName(jsii.String("service-role/AWSLambdaVPCAccessExecutionRole")),
+		},
+		InlinePolicies: &map[string]awsiam.PolicyDocument{
+			"S3Access": awsiam.NewPolicyDocument(&awsiam.PolicyDocumentProps{
+				Statements: &[]awsiam.PolicyStatement{
+					awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+						Effect: awsiam.Effect_ALLOW,
+						Actions: &[]*string{
+							jsii.String("s3:GetObject"),
+							jsii.String("s3:PutObject"),
+						},
+						Resources: &[]*string{
+							props.Bucket.BucketArn(),
+							jsii.String(*props.Bucket.BucketArn() + "/*"),
+						},
+					}),
+				},
+			}),
+			"KMSAccess": awsiam.NewPolicyDocument(&awsiam.PolicyDocumentProps{
+				Statements: &[]awsiam.PolicyStatement{
+					awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+						Effect: awsiam.Effect_ALLOW,
+						Actions: &[]*string{
+							jsii.String("kms:Encrypt"),
+							jsii.String("kms:Decrypt"),
+							jsii.String("kms:ReEncrypt*"),
+							jsii.String("kms:GenerateDataKey*"),
+							jsii.String("kms:DescribeKey"),
+						},
+						Resources: &[]*string{
+							props.KmsKeys[KeyDomainAppData].KeyArn(),
+						},
+					}),
+				},
+			}),
+		},
+	})
+
+	// Create CloudWatch Log Group
+	logGroup := awslogs.NewLogGroup(c.Construct, jsii.String("ProdLambdaLogGroup"), &awslogs.LogGroupProps{
+		LogGroupName:  jsii.String("/aws/lambda/" + props.Namer.Name(ResourceLambdaFunction, "background-job")),
+		EncryptionKey: props.KmsKeys[KeyDomainLogs],
+		Retention:     props.LogRetention,
+		RemovalPolicy: props.RemovalPolicy,
+	})
+
+	// Simple Python Lambda function for background jobs
+	lambdaCode := `
+import json
+import boto3
+import os
+import logging
+from datetime import datetime
+
+logger = logging.getLogger()
+logger.setLevel(logging.INFO)
+
+def lambda_handler(event, context):
+    """
+    Simple background job Lambda function
+    Processes background tasks securely
+    """
+    try:
+        logger.info(f"Processing background job: {json.dumps(event)}")
+        
+        # Get environment variables
+        environment = os.environ.get('ENVIRONMENT', 'unknown')
+        s3_bucket = os.environ.get('S3_BUCKET', 'default-bucket')
+        
+        # Simple processing logic
+        result = {
+            'status': 'success',
+            'timestamp': datetime.utcnow().isoformat() + 'Z',
+            'environment': environment,
+            'processed_records': len(event.get('records', [])),
+            'request_id': context.aws_request_id
+        }
+        
+        logger.info(f"Background job completed successfully: {result}")
+        
+        return {
+            'statusCode': 200,
+            'body': json.dumps(result)
+        }
+        
+    except Exception as e:
+        logger.error(f"Error processing background job: {str(e)}")
+        return {
+            'statusCode': 500,
+            'body': json.dumps({
+                'error': 'Background job failed',
+                'message': str(e)
+            })
+        }
+`
+
+	c.LambdaFunction = awslambda.NewFunction(c.Construct, jsii.String("ProdLambdaFunction"), &awslambda.FunctionProps{
+		FunctionName: resourceName(props.Namer, ResourceLambdaFunction, "background-job"),
+		Runtime:      awslambda.Runtime_PYTHON_3_9(),
+		Code:         awslambda.Code_FromInline(jsii.String(lambdaCode)),
+		Handler:      jsii.String("index.lambda_handler"),
+		MemorySize:   props.Lambda.MemorySize,
+		Timeout:      awscdk.Duration_Seconds(props.Lambda.TimeoutSeconds),
+		Role:         lambdaRole,
+		LogGroup:     logGroup,
+		Vpc:          props.Vpc,
+		VpcSubnets: &awsec2.SubnetSelection{
+			Subnets: props.PrivateSubnets,
+		},
+		SecurityGroups: &[]awsec2.ISecurityGroup{
+			props.LambdaSecurityGroup,
+		},
+		Environment: &map[string]*string{
+			"ENVIRONMENT": props.EnvironmentSuffix,
+			"S3_BUCKET":   props.Bucket.BucketName(),
+			"LOG_LEVEL":   jsii.String("INFO"),
+		},
+		Description:  jsii.String("Background job processing Lambda function"),
+		Tracing:      awslambda.Tracing_ACTIVE,
+		Architecture: awslambda.Architecture_X86_64(),
+	})
+
+	awscdk.Tags_Of(c.LambdaFunction).Add(jsii.String("Name"), resourceName(props.Namer, ResourceGeneric, "lambda"), nil)
+	awscdk.Tags_Of(lambdaRole).Add(jsii.String("Name"), resourceName(props.Namer, ResourceGeneric, "lambda-role"), nil)
+}
+
+// createEC2Resources creates EC2 instances in private subnets only
+func (c *ComputeConstruct) createEC2Resources(props *ComputeConstructProps) {
+	// Create IAM role for EC2 instances
+	ec2Role := awsiam.NewRole(c.Construct, jsii.String("ProdEC2Role"), &awsiam.RoleProps{
+		RoleName:  resourceName(props.Namer, ResourceIamRole, "ec2-role"),
+		AssumedBy: awsiam.NewServicePrincipal(jsii.String("ec2.amazonaws.com"), nil),
+		ManagedPolicies: &[]awsiam.IManagedPolicy{
+			awsiam.ManagedPolicy_FromAwsManagedPolicyName(jsii.String("AmazonSSMManagedInstanceCore")),
+		},
+	})
+
+	// Serve a health check endpoint for the ALB target group
+	userData := awsec2.UserData_ForLinux(&awsec2.LinuxUserDataOptions{
+		Shebang: jsii.String("#!/bin/bash"),
+	})
+	userData.AddCommands(
+		jsii.String("yum install -y httpd"),
+		jsii.String("echo OK > /var/www/html/health"),
+		jsii.String("systemctl enable --now httpd"),
+	)
+
+	// Create launch template
+	launchTemplate := awsec2.NewLaunchTemplate(c.Construct, jsii.String("ProdLaunchTemplate"), &awsec2.LaunchTemplateProps{
+		LaunchTemplateName: resourceName(props.Namer, ResourceGeneric, "lt"),
+		InstanceType:       awsec2.NewInstanceType(props.Compute.InstanceType),
+		MachineImage:       awsec2.MachineImage_LatestAmazonLinux2(nil),
+		Role:               ec2Role,
+		SecurityGroup:      props.InstanceSecurityGroup,
+		UserData:           userData,
+	})
+
+	// Create Auto Scaling Group in private subnets only
+	c.AutoScalingGroup = awsautoscaling.NewAutoScalingGroup(c.Construct, jsii.String("ProdAutoScalingGroup"), &awsautoscaling.AutoScalingGroupProps{
+		AutoScalingGroupName: resourceName(props.Namer, ResourceGeneric, "asg"),
+		Vpc:                  props.Vpc,
+		VpcSubnets: &awsec2.SubnetSelection{
+			Subnets: props.PrivateSubnets,
+		},
+		LaunchTemplate:  launchTemplate,
+		MinCapacity:     props.Compute.MinCapacity,
+		MaxCapacity:     props.Compute.MaxCapacity,
+		DesiredCapacity: props.Compute.DesiredCapacity,
+	})
+
+	awscdk.Tags_Of(c.AutoScalingGroup).Add(jsii.String("Name"), resourceName(props.Namer, ResourceGeneric, "asg"), nil)
+	awscdk.Tags_Of(ec2Role).Add(jsii.String("Name"), resourceName(props.Namer, ResourceGeneric, "ec2-role"), nil)
+}
+
+// createLoadBalancer creates an internet-facing ALB in front of the Auto Scaling Group
+func (c *ComputeConstruct) createLoadBalancer(props *ComputeConstructProps) {
+	var ipAddressType awselasticloadbalancingv2.IpAddressType
+	if props.DualStack {
+		ipAddressType = awselasticloadbalancingv2.IpAddressType_DUAL_STACK
+	}
+	c.LoadBalancer = awselasticloadbalancingv2.NewApplicationLoadBalancer(c.Construct, jsii.String("ProdALB"), &awselasticloadbalancingv2.ApplicationLoadBalancerProps{
+		LoadBalancerName: resourceName(props.Namer, ResourceLoadBalancer, "alb"),
+		Vpc:              props.Vpc,
+		InternetFacing:   jsii.Bool(true),
+		VpcSubnets: &awsec2.SubnetSelection{
+			Subnets: props.PublicSubnets,
+		},
+		SecurityGroup:           props.LoadBalancerSecurityGroup,
+		DropInvalidHeaderFields: jsii.Bool(true),
+		IpAddressType:           ipAddressType,
+	})
+
+	targetGroup := awselasticloadbalancingv2.NewApplicationTargetGroup(c.Construct, jsii.String("ProdAppTargetGroup"), &awselasticloadbalancingv2.ApplicationTargetGroupProps{
+		TargetGroupName: resourceName(props.Namer, ResourceTargetGroup, "tg"),
+		Vpc:             props.Vpc,
+		Port:            jsii.Number(80),
+		Protocol:        awselasticloadbalancingv2.ApplicationProtocol_HTTP,
+		Targets: &[]awselasticloadbalancingv2.IApplicationLoadBalancerTarget{
+			c.AutoScalingGroup,
+		},
+		HealthCheck: &awselasticloadbalancingv2.HealthCheck{
+			Path:                    jsii.String("/health"),
+			HealthyHttpCodes:        jsii.String("200"),
+			Interval:                awscdk.Duration_Seconds(jsii.Number(30)),
+			Timeout:                 awscdk.Duration_Seconds(jsii.Number(5)),
+			HealthyThresholdCount:   jsii.Number(2),
+			UnhealthyThresholdCount: jsii.Number(3),
+		},
+		DeregistrationDelay: awscdk.Duration_Seconds(jsii.Number(30)),
+	})
+
+	if props.CertificateArn == nil {
+		// No certificate configured - serve plain HTTP only
+		c.LoadBalancer.AddListener(jsii.String("HttpListener"), &awselasticloadbalancingv2.BaseApplicationListenerProps{
+			Port:                jsii.Number(80),
+			Protocol:            awselasticloadbalancingv2.ApplicationProtocol_HTTP,
+			Open:                jsii.Bool(false),
+			DefaultTargetGroups: &[]awselasticloadbalancingv2.IApplicationTargetGroup{targetGroup},
+		})
+	} else {
+		c.LoadBalancer.AddListener(jsii.String("HttpsListener"), &awselasticloadbalancingv2.BaseApplicationListenerProps{
+			Port:     jsii.Number(443),
+			Protocol: awselasticloadbalancingv2.ApplicationProtocol_HTTPS,
+			Open:     jsii.Bool(false),
+			Certificates: &[]awselasticloadbalancingv2.IListenerCertificate{
+				awselasticloadbalancingv2.ListenerCertificate_FromArn(props.CertificateArn),
+			},
+			SslPolicy:           awselasticloadbalancingv2.SslPolicy_RECOMMENDED_TLS,
+			DefaultTargetGroups: &[]awselasticloadbalancingv2.IApplicationTargetGroup{targetGroup},
+		})
+
+		// Redirect HTTP to HTTPS
+		c.LoadBalancer.AddListener(jsii.String("HttpListener"), &awselasticloadbalancingv2.BaseApplicationListenerProps{
+			Port:     jsii.Number(80),
+			Protocol: awselasticloadbalancingv2.ApplicationProtocol_HTTP,
+			Open:     jsii.Bool(false),
+			DefaultAction: awselasticloadbalancingv2.ListenerAction_Redirect(&awselasticloadbalancingv2.RedirectOptions{
+				Protocol:  jsii.String("HTTPS"),
+				Port:      jsii.String("443"),
+				Permanent: jsii.Bool(true),
+			}),
+		})
+	}
+
+	awscdk.Tags_Of(c.LoadBalancer).Add(jsii.String("Name"), resourceName(props.Namer, ResourceGeneric, "alb"), nil)
+}
+
+// createBastionHost creates the bastion host for the configured BastionMode
+func (c *ComputeConstruct) createBastionHost(props *ComputeConstructProps) {
+	var subnets *[]awsec2.ISubnet
+	switch props.Compute.BastionMode {
+	case BastionModeSsm:
+		// No public IP - Session Manager reaches the instance through NAT or the VPC endpoints
+		subnets = props.PrivateSubnets
+	case BastionModeCidrAllowlist:
+		subnets = props.PublicSubnets
+	default:
+		return
+	}
+
+	c.BastionHost = awsec2.NewBastionHostLinux(c.Construct, jsii.String("ProdBastionHost"), &awsec2.BastionHostLinuxProps{
+		Vpc:           props.Vpc,
+		InstanceName:  resourceName(props.Namer, ResourceGeneric, "bastion"),
+		InstanceType:  awsec2.NewInstanceType(props.Compute.BastionInstanceType),
+		SecurityGroup: props.BastionSecurityGroup,
+		SubnetSelection: &awsec2.SubnetSelection{
+			Subnets: subnets,
+		},
+	})
+
+	if props.Compute.BastionMode == BastionModeSsm {
+		c.createSessionPreferences(props)
+	}
+
+	awscdk.Tags_Of(c.BastionHost).Add(jsii.String("Name"), resourceName(props.Namer, ResourceGeneric, "bastion"), nil)
+}
+
+// createSessionPreferences creates the Session Manager preferences document that
+// streams session transcripts, encrypted with the logs key, to CloudWatch Logs and S3
+func (c *ComputeConstruct) createSessionPreferences(props *ComputeConstructProps) {
+	const s3KeyPrefix = "session-manager"
+
+	c.SessionLogGroup = awslogs.NewLogGroup(c.Construct, jsii.String("SessionLogGroup"), &awslogs.LogGroupProps{
+		LogGroupName:  jsii.String("/aws/ssm/" + props.Namer.Name(ResourceGeneric, "sessions")),
+		EncryptionKey: props.KmsKeys[KeyDomainLogs],
+		Retention:     props.LogRetention,
+		RemovalPolicy: props.RemovalPolicy,
+	})
+
+	c.SessionPreferences = awsssm.NewCfnDocument(c.Construct, jsii.String("SessionPreferences"), &awsssm.CfnDocumentProps{
+		Name:           resourceName(props.Namer, ResourceGeneric, "session-preferences"),
+		DocumentType:   jsii.String("Session"),
+		DocumentFormat: jsii.String("JSON"),
+		UpdateMethod:   jsii.String("NewVersion"),
+		Content: map[string]interface{}{
+			"schemaVersion": "1.0",
+			"description":   "Session Manager preferences with encrypted session logging",
+			"sessionType":   "Standard_Stream",
+			"inputs": map[string]interface{}{
+				"s3BucketName":                props.SessionLogBucket.BucketName(),
+				"s3KeyPrefix":                 s3KeyPrefix,
+				"s3EncryptionEnabled":         true,
+				"cloudWatchLogGroupName":      c.SessionLogGroup.LogGroupName(),
+				"cloudWatchEncryptionEnabled": true,
+				"cloudWatchStreamingEnabled":  true,
+				"kmsKeyId":                    props.KmsKeys[KeyDomainLogs].KeyId(),
+				"runAsEnabled":                false,
+				"idleSessionTimeout":          "20",
+			},
+		},
+	})
+
+	// The SSM agent on the bastion uploads the transcripts and decrypts the session data key
+	role := c.BastionHost.Role()
+	c.SessionLogGroup.GrantWrite(role)
+	props.SessionLogBucket.GrantPut(role, jsii.String(s3KeyPrefix+"/*"))
+	props.KmsKeys[KeyDomainLogs].GrantDecrypt(role)
+	role.AddToPrincipalPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Effect: awsiam.Effect_ALLOW,
+		Actions: &[]*string{
+			jsii.String("s3:GetEncryptionConfiguration"),
+		},
+		Resources: &[]*string{
+			props.SessionLogBucket.BucketArn(),
+		},
+	}))
+	role.AddToPrincipalPolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Effect: awsiam.Effect_ALLOW,
+		Actions: &[]*string{
+			jsii.String("logs:DescribeLogGroups"),
+			jsii.String("logs:DescribeLogStreams"),
+		},
+		Resources: &[]*string{
+			jsii.String("*"),
+		},
+	}))
+}
+
+// createInstanceConnectEndpoint creates an EC2 Instance Connect Endpoint so
+// operators can SSH to private instances without a public hop
+func (c *ComputeConstruct) createInstanceConnectEndpoint(props *ComputeConstructProps) {
+	if !*props.Compute.InstanceConnectEndpoint {
+		return
+	}
+
+	// One endpoint serves every subnet of the VPC
+	c.InstanceConnectEndpoint = awsec2.NewCfnInstanceConnectEndpoint(c.Construct, jsii.String("InstanceConnectEndpoint"), &awsec2.CfnInstanceConnectEndpointProps{
+		SubnetId:         (*props.PrivateSubnets)[0].SubnetId(),
+		SecurityGroupIds: &[]*string{props.InstanceConnectEndpointSecurityGroup.SecurityGroupId()},
+		PreserveClientIp: jsii.Bool(false),
+	})
+
+	awscdk.Tags_Of(c.InstanceConnectEndpoint).Add(jsii.String("Name"), resourceName(props.Namer, ResourceGeneric, "eice"), nil)
+}
@@ -0,0 +1,718 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awscloudfront"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslogs"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// NatMode selects how private subnets reach the internet.
+type NatMode string
+
+const (
+	// NatModePerAz places a NAT gateway in every AZ (or NatGateways of them).
+	NatModePerAz NatMode = "per-az"
+	// NatModeSingle shares one NAT gateway between all AZs.
+	NatModeSingle NatMode = "single"
+	// NatModeInstance uses Graviton NAT instances instead of NAT gateways.
+	NatModeInstance NatMode = "instance"
+	// NatModeNone gives private subnets no internet egress; AWS APIs are
+	// reached through VPC endpoints, which this mode requires.
+	NatModeNone NatMode = "none"
+)
+
+// QueryLogDestination selects where Route 53 Resolver query logs are sent.
+type QueryLogDestination string
+
+const (
+	// QueryLogDestinationNone disables Resolver query logging.
+	QueryLogDestinationNone QueryLogDestination = "none"
+	// QueryLogDestinationS3 writes query logs to the logging bucket.
+	QueryLogDestinationS3 QueryLogDestination = "s3"
+	// QueryLogDestinationCloudWatch writes query logs to a CloudWatch log group.
+	QueryLogDestinationCloudWatch QueryLogDestination = "cloudwatch"
+)
+
+// BastionMode selects how operators reach the bastion host.
+type BastionMode string
+
+const (
+	// BastionModeSsm places the bastion in a private subnet without a public
+	// IP or SSH ingress; it is reached through Session Manager only.
+	BastionModeSsm BastionMode = "ssm"
+	// BastionModeCidrAllowlist places the bastion in a public subnet with SSH
+	// restricted to BastionAllowedCidrs.
+	BastionModeCidrAllowlist BastionMode = "cidr-allowlist"
+	// BastionModeNone creates no bastion host.
+	BastionModeNone BastionMode = "none"
+)
+
+// NetworkConfig configures the VPC built by the NetworkConstruct.
+type NetworkConfig struct {
+	// VpcCidr is the IPv4 CIDR block of the VPC. Defaults to 10.0.0.0/16.
+	VpcCidr *string `json:"vpcCidr,omitempty"`
+	// MaxAzs is the number of Availability Zones to span. Defaults to 2.
+	MaxAzs *float64 `json:"maxAzs,omitempty"`
+	// SubnetCidrMask is the prefix length of every subnet. Defaults to 24.
+	SubnetCidrMask *float64 `json:"subnetCidrMask,omitempty"`
+	// NatMode selects NAT gateways, NAT instances or no NAT. Defaults to per-az.
+	NatMode NatMode `json:"natMode,omitempty"`
+	// NatGateways is the number of NAT gateways (or NAT instances in instance
+	// mode). Defaults to one per AZ.
+	NatGateways *float64 `json:"natGateways,omitempty"`
+	// NatInstanceType is the Graviton instance type used in instance mode.
+	// Defaults to t4g.nano.
+	NatInstanceType *string `json:"natInstanceType,omitempty"`
+	// VpcEndpoints adds S3/DynamoDB gateway endpoints and interface endpoints
+	// for the AWS APIs the workloads call, and limits Lambda egress to them.
+	// Defaults to false.
+	VpcEndpoints *bool `json:"vpcEndpoints,omitempty"`
+	// DualStack adds an Amazon-provided IPv6 CIDR to the VPC and subnets, an
+	// egress-only internet gateway for the private tier, IPv6 security group
+	// rules, a dual-stack ALB and IPv6 on CloudFront. Defaults to false.
+	DualStack *bool `json:"dualStack,omitempty"`
+	// NetworkAcls replaces the default allow-all network ACL of each subnet
+	// tier with DefaultNetworkAclRules. Defaults to true.
+	NetworkAcls *bool `json:"networkAcls,omitempty"`
+	// FlowLogsCloudWatch also delivers the VPC flow logs to a CloudWatch log
+	// group, kept for Logging.Retention, for short-term Logs Insights queries.
+	// The S3 copy is always written. Defaults to false.
+	FlowLogsCloudWatch *bool `json:"flowLogsCloudWatch,omitempty"`
+	// ResolverQueryLogs records the DNS queries made from the VPC. Defaults to none.
+	ResolverQueryLogs QueryLogDestination `json:"resolverQueryLogs,omitempty"`
+	// DnsFirewall associates a Route 53 Resolver DNS Firewall rule group with
+	// the VPC that blocks DnsFirewallBlockedDomains, except for
+	// DnsFirewallAllowedDomains, and alarms on blocked queries. Use "*" as the
+	// only blocked domain to allow nothing but the allowlist. Defaults to false.
+	DnsFirewall               *bool    `json:"dnsFirewall,omitempty"`
+	DnsFirewallBlockedDomains []string `json:"dnsFirewallBlockedDomains,omitempty"`
+	DnsFirewallAllowedDomains []string `json:"dnsFirewallAllowedDomains,omitempty"`
+	// NetworkFirewall sends the private tier's internet egress through an AWS
+	// Network Firewall in its own subnet tier, which only allows HTTP/HTTPS to
+	// DefaultFirewallDomainRules plus NetworkFirewallAllowedDomains (e.g.
+	// package mirrors). Requires NAT gateways. Defaults to false.
+	NetworkFirewall               *bool    `json:"networkFirewall,omitempty"`
+	NetworkFirewallAllowedDomains []string `json:"networkFirewallAllowedDomains,omitempty"`
+}
+
+// ComputeConfig configures the EC2 Auto Scaling Group and bastion host.
+type ComputeConfig struct {
+	// InstanceType is the application instance type. Defaults to t3.micro.
+	InstanceType *string `json:"instanceType,omitempty"`
+	// BastionInstanceType is the bastion host instance type. Defaults to t3.nano.
+	BastionInstanceType *string `json:"bastionInstanceType,omitempty"`
+	// BastionMode selects Session Manager, SSH from an allowlist or no
+	// bastion. Defaults to ssm.
+	BastionMode BastionMode `json:"bastionMode,omitempty"`
+	// BastionAllowedCidrs are the IPv4 or IPv6 CIDR blocks allowed to SSH to
+	// the bastion in cidr-allowlist mode.
+	BastionAllowedCidrs []string `json:"bastionAllowedCidrs,omitempty"`
+	// InstanceConnectEndpoint adds an EC2 Instance Connect Endpoint in a
+	// private subnet; instances then accept SSH from it only. Defaults to false.
+	InstanceConnectEndpoint *bool `json:"instanceConnectEndpoint,omitempty"`
+	// MinCapacity is the ASG minimum size. Defaults to 1.
+	MinCapacity *float64 `json:"minCapacity,omitempty"`
+	// MaxCapacity is the ASG maximum size. Defaults to 3.
+	MaxCapacity *float64 `json:"maxCapacity,omitempty"`
+	// DesiredCapacity is the ASG desired size. Defaults to 2.
+	DesiredCapacity *float64 `json:"desiredCapacity,omitempty"`
+}
+
+// LambdaConfig configures the background job Lambda function.
+type LambdaConfig struct {
+	// MemorySize is the function memory in MB. Defaults to 256.
+	MemorySize *float64 `json:"memorySize,omitempty"`
+	// TimeoutSeconds is the function timeout. Defaults to 30.
+	TimeoutSeconds *float64 `json:"timeoutSeconds,omitempty"`
+}
+
+// CdnConfig configures the CloudFront distribution.
+type CdnConfig struct {
+	// PriceClass limits the edge locations used. Defaults to PRICE_CLASS_100.
+	PriceClass awscloudfront.PriceClass `json:"priceClass,omitempty"`
+	// BlockedCidrs are IPv4 and IPv6 ranges the WAF WebACL blocks. With an
+	// EdgeStack, pass them as EdgeStackProps.BlockedCidrs, which owns the ACL.
+	BlockedCidrs []string `json:"blockedCidrs,omitempty"`
+}
+
+// LoggingConfig configures CloudWatch Logs groups created by the stack.
+type LoggingConfig struct {
+	// Retention is the log group retention. Defaults to ONE_MONTH.
+	Retention awslogs.RetentionDays `json:"retention,omitempty"`
+}
+
+// StackConfig is the fully resolved sizing configuration of a TapStack.
+type StackConfig struct {
+	Network NetworkConfig `json:"network"`
+	Compute ComputeConfig `json:"compute"`
+	Lambda  LambdaConfig  `json:"lambda"`
+	Cdn     CdnConfig     `json:"cdn"`
+	Logging LoggingConfig `json:"logging"`
+	// SecretRotationDays is the rotation interval for the application secret.
+	SecretRotationDays *float64 `json:"secretRotationDays,omitempty"`
+	// RemovalPolicy applies to stateful resources (keys, buckets, logs, secrets,
+	// database). Defaults to DESTROY.
+	RemovalPolicy awscdk.RemovalPolicy `json:"removalPolicy,omitempty"`
+}
+
+// subnetTierCount is the number of subnet groups carved out of the VPC CIDR
+// (public, private and isolated).
+const subnetTierCount = 3
+
+var instanceTypePattern = regexp.MustCompile(`^[a-z][a-z0-9-]*\.[a-z0-9]+$`)
+var gravitonFamilyPattern = regexp.MustCompile(`^[a-z]+[0-9]+g[a-z]*\.`)
+var kmsKeyArnPattern = regexp.MustCompile(`^arn:aws[a-z-]*:kms:[a-z0-9-]+:\d{12}:key/[0-9a-f-]+$`)
+var networkFirewallDomainPattern = regexp.MustCompile(`^\.?([a-z0-9]([a-z0-9-]*[a-z0-9])?\.)+[a-z]{2,63}$`)
+var firewallDomainPattern = regexp.MustCompile(`^(\*|(\*\.)?([a-z0-9]([a-z0-9-]*[a-z0-9])?\.)*[a-z0-9]([a-z0-9-]*[a-z0-9])?\.?)$`)
+var dnsNamePattern = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]*[a-z0-9])?\.)*[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+var validPriceClasses = map[awscloudfront.PriceClass]bool{
+	awscloudfront.PriceClass_PRICE_CLASS_100: true,
+	awscloudfront.PriceClass_PRICE_CLASS_200: true,
+	awscloudfront.PriceClass_PRICE_CLASS_ALL: true,
+}
+
+var validRetentions = map[awslogs.RetentionDays]bool{
+	awslogs.RetentionDays_ONE_DAY:         true,
+	awslogs.RetentionDays_THREE_DAYS:      true,
+	awslogs.RetentionDays_FIVE_DAYS:       true,
+	awslogs.RetentionDays_ONE_WEEK:        true,
+	awslogs.RetentionDays_TWO_WEEKS:       true,
+	awslogs.RetentionDays_ONE_MONTH:       true,
+	awslogs.RetentionDays_TWO_MONTHS:      true,
+	awslogs.RetentionDays_THREE_MONTHS:    true,
+	awslogs.RetentionDays_FOUR_MONTHS:     true,
+	awslogs.RetentionDays_FIVE_MONTHS:     true,
+	awslogs.RetentionDays_SIX_MONTHS:      true,
+	awslogs.RetentionDays_ONE_YEAR:        true,
+	awslogs.RetentionDays_THIRTEEN_MONTHS: true,
+	awslogs.RetentionDays_EIGHTEEN_MONTHS: true,
+	awslogs.RetentionDays_TWO_YEARS:       true,
+	awslogs.RetentionDays_THREE_YEARS:     true,
+	awslogs.RetentionDays_FIVE_YEARS:      true,
+	awslogs.RetentionDays_SIX_YEARS:       true,
+	awslogs.RetentionDays_SEVEN_YEARS:     true,
+	awslogs.RetentionDays_EIGHT_YEARS:     true,
+	awslogs.RetentionDays_NINE_YEARS:      true,
+	awslogs.RetentionDays_TEN_YEARS:       true,
+	awslogs.RetentionDays_INFINITE:        true,
+}
+
+// DefaultStackConfig returns the configuration used when TapStackProps leaves
+// a setting unset.
+func DefaultStackConfig() StackConfig {
+	return StackConfig{
+		Network: NetworkConfig{
+			VpcCidr:            jsii.String("10.0.0.0/16"),
+			MaxAzs:             jsii.Number(2),
+			SubnetCidrMask:     jsii.Number(24),
+			NatMode:            NatModePerAz,
+			NatInstanceType:    jsii.String("t4g.nano"),
+			VpcEndpoints:       jsii.Bool(false),
+			DualStack:          jsii.Bool(false),
+			NetworkAcls:        jsii.Bool(true),
+			FlowLogsCloudWatch: jsii.Bool(false),
+			ResolverQueryLogs:  QueryLogDestinationNone,
+			DnsFirewall:        jsii.Bool(false),
+			NetworkFirewall:    jsii.Bool(false),
+		},
+		Compute: ComputeConfig{
+			InstanceType:            jsii.String("t3.micro"),
+			BastionInstanceType:     jsii.String("t3.nano"),
+			BastionMode:             BastionModeSsm,
+			InstanceConnectEndpoint: jsii.Bool(false),
+			MinCapacity:             jsii.Number(1),
+			MaxCapacity:             jsii.Number(3),
+			DesiredCapacity:         jsii.Number(2),
+		},
+		Lambda: LambdaConfig{
+			MemorySize:     jsii.Number(256),
+			TimeoutSeconds: jsii.Number(30),
+		},
+		Cdn: CdnConfig{
+			PriceClass: awscloudfront.PriceClass_PRICE_CLASS_100,
+		},
+		Logging: LoggingConfig{
+			Retention: awslogs.RetentionDays_ONE_MONTH,
+		},
+		SecretRotationDays: jsii.Number(30),
+		RemovalPolicy:      awscdk.RemovalPolicy_DESTROY,
+	}
+}
+
+// Config merges the props over DefaultStackConfig and validates the result.
+func (p *TapStackProps) Config() (StackConfig, error) {
+	config := DefaultStackConfig()
+	config.merge(p.explicitConfig())
+	if err := errors.Join(config.Validate(), p.validateDatabase(), p.validateNamer(), p.validateExistingResources(), p.validateDns(), p.validateTransitGateway(config.Network)); err != nil {
+		return StackConfig{}, err
+	}
+	return config, nil
+}
+
+// validateDatabase checks that DatabaseEngine is one of DatabaseEngines or
+// DatabaseEngineNone
+func (p *TapStackProps) validateDatabase() error {
+	if p == nil || p.DatabaseEngine == DatabaseEngineNone || slices.Contains(DatabaseEngines, p.DatabaseEngine) {
+		return nil
+	}
+	engines := make([]string, 0, len(DatabaseEngines))
+	for _, engine := range DatabaseEngines {
+		engines = append(engines, fmt.Sprintf("%q", engine))
+	}
+	return fmt.Errorf("unsupported DatabaseEngine %q, must be one of %s, or empty for no database",
+		p.DatabaseEngine, strings.Join(engines, ", "))
+}
+
+// validateNamer checks the name parts of a DefaultNamer; custom namers are
+// responsible for their own names
+func (p *TapStackProps) validateNamer() error {
+	if p == nil {
+		return nil
+	}
+	if namer, ok := p.Namer.(*DefaultNamer); ok && namer != nil {
+		return namer.Validate()
+	}
+	return nil
+}
+
+// validateExistingResources checks the imported VPC and KMS key settings
+func (p *TapStackProps) validateExistingResources() error {
+	if p == nil {
+		return nil
+	}
+
+	var errs []error
+	if p.ExistingVpc != nil {
+		if err := p.ExistingVpc.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+		// Vpc_FromLookup resolves the VPC at synth time from a concrete environment
+		if p.StackProps == nil || p.StackProps.Env == nil ||
+			!isConcrete(p.StackProps.Env.Account) || !isConcrete(p.StackProps.Env.Region) {
+			errs = append(errs, errors.New("ExistingVpc requires StackProps.Env with an explicit Account and Region"))
+		}
+		if p.Network != nil && p.Network.DualStack != nil && *p.Network.DualStack {
+			errs = append(errs, errors.New("DualStack cannot add IPv6 to an ExistingVpc"))
+		}
+		if p.Network != nil && p.Network.NetworkFirewall != nil && *p.Network.NetworkFirewall {
+			errs = append(errs, errors.New("NetworkFirewall cannot add its subnet tiers to an ExistingVpc"))
+		}
+		if p.ExistingVpc.HasFlowLogs && p.Network != nil && p.Network.FlowLogsCloudWatch != nil && *p.Network.FlowLogsCloudWatch {
+			errs = append(errs, errors.New("FlowLogsCloudWatch requires the stack's own flow logs, but ExistingVpc.HasFlowLogs is set"))
+		}
+	}
+	if p.ExistingKmsKeyArn != nil && !*awscdk.Token_IsUnresolved(p.ExistingKmsKeyArn) &&
+		!kmsKeyArnPattern.MatchString(*p.ExistingKmsKeyArn) {
+		errs = append(errs, fmt.Errorf("ExistingKmsKeyArn %q is not a KMS key ARN", *p.ExistingKmsKeyArn))
+	}
+	if p.ExistingKmsKeyArn != nil && p.KmsKeyPerDomain {
+		errs = append(errs, errors.New("KmsKeyPerDomain creates its own keys and cannot be combined with ExistingKmsKeyArn"))
+	}
+	return errors.Join(errs...)
+}
+
+// validateTransitGateway checks the attachment settings against the resolved
+// VPC sizing
+func (p *TapStackProps) validateTransitGateway(network NetworkConfig) error {
+	if p == nil || p.TransitGateway == nil {
+		return nil
+	}
+
+	errs := []error{p.TransitGateway.Validate(network.VpcCidr)}
+	if p.ExistingVpc != nil {
+		errs = append(errs, errors.New("TransitGateway cannot add its subnet tier to an ExistingVpc"))
+	}
+	if room := network.smallSubnetRoom(); room >= 0 && isWholeNumber(network.MaxAzs) {
+		if network.NetworkFirewall != nil && *network.NetworkFirewall {
+			room -= firewallSubnetTierCount * int(*network.MaxAzs)
+		}
+		if needed := int(*network.MaxAzs); room >= 0 && needed > room {
+			errs = append(errs, fmt.Errorf("network: VpcCidr %s has room for %d more /%d subnets but TransitGateway needs %d",
+				*network.VpcCidr, room, FirewallSubnetCidrMask, needed))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validateDns checks the hosted zone names and that the custom CloudFront
+// domain can be served from the public zone
+func (p *TapStackProps) validateDns() error {
+	if p == nil {
+		return nil
+	}
+
+	var errs []error
+	for _, zone := range []struct {
+		field string
+		name  *string
+	}{
+		{"PrivateZoneName", p.PrivateZoneName},
+		{"PublicZoneName", p.PublicZoneName},
+	} {
+		if isConcrete(zone.name) && !dnsNamePattern.MatchString(strings.TrimSuffix(*zone.name, ".")) {
+			errs = append(errs, fmt.Errorf("%s %q is not a valid lower-case DNS name", zone.field, *zone.name))
+		}
+	}
+	if isConcrete(p.PublicZoneName) && p.EdgeStack != nil && isConcrete(p.EdgeStack.CdnDomainName) {
+		zone := strings.TrimSuffix(*p.PublicZoneName, ".")
+		domain := strings.TrimSuffix(*p.EdgeStack.CdnDomainName, ".")
+		if domain != zone && !strings.HasSuffix(domain, "."+zone) {
+			errs = append(errs, fmt.Errorf("CdnDomainName %q is not inside PublicZoneName %q", domain, zone))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// isConcrete reports whether value is set and not a deploy-time token
+func isConcrete(value *string) bool {
+	return value != nil && *value != "" && !*awscdk.Token_IsUnresolved(value)
+}
+
+// Validate reports every invalid setting of the props, after defaults are applied.
+func (p *TapStackProps) Validate() error {
+	_, err := p.Config()
+	return err
+}
+
+// explicitConfig returns only the settings present on the props
+func (p *TapStackProps) explicitConfig() StackConfig {
+	if p == nil {
+		return StackConfig{}
+	}
+	return StackConfig{
+		Network:            derefOrZero(p.Network),
+		Compute:            derefOrZero(p.Compute),
+		Lambda:             derefOrZero(p.Lambda),
+		Cdn:                derefOrZero(p.Cdn),
+		Logging:            derefOrZero(p.Logging),
+		SecretRotationDays: p.SecretRotationDays,
+		RemovalPolicy:      p.RemovalPolicy,
+	}
+}
+
+// merge overwrites the receiver with every setting present in other.
+func (c *StackConfig) merge(other StackConfig) {
+	mergeString(&c.Network.VpcCidr, other.Network.VpcCidr)
+	mergeNumber(&c.Network.MaxAzs, other.Network.MaxAzs)
+	mergeNumber(&c.Network.SubnetCidrMask, other.Network.SubnetCidrMask)
+	if other.Network.NatMode != "" {
+		c.Network.NatMode = other.Network.NatMode
+	}
+	mergeNumber(&c.Network.NatGateways, other.Network.NatGateways)
+	mergeString(&c.Network.NatInstanceType, other.Network.NatInstanceType)
+	mergeBool(&c.Network.VpcEndpoints, other.Network.VpcEndpoints)
+	mergeBool(&c.Network.DualStack, other.Network.DualStack)
+	mergeBool(&c.Network.NetworkAcls, other.Network.NetworkAcls)
+	mergeBool(&c.Network.FlowLogsCloudWatch, other.Network.FlowLogsCloudWatch)
+	if other.Network.ResolverQueryLogs != "" {
+		c.Network.ResolverQueryLogs = other.Network.ResolverQueryLogs
+	}
+	mergeBool(&c.Network.DnsFirewall, other.Network.DnsFirewall)
+	if other.Network.DnsFirewallBlockedDomains != nil {
+		c.Network.DnsFirewallBlockedDomains = other.Network.DnsFirewallBlockedDomains
+	}
+	if other.Network.DnsFirewallAllowedDomains != nil {
+		c.Network.DnsFirewallAllowedDomains = other.Network.DnsFirewallAllowedDomains
+	}
+	mergeBool(&c.Network.NetworkFirewall, other.Network.NetworkFirewall)
+	if other.Network.NetworkFirewallAllowedDomains != nil {
+		c.Network.NetworkFirewallAllowedDomains = other.Network.NetworkFirewallAllowedDomains
+	}
+
+	mergeString(&c.Compute.InstanceType, other.Compute.InstanceType)
+	mergeString(&c.Compute.BastionInstanceType, other.Compute.BastionInstanceType)
+	if other.Compute.BastionMode != "" {
+		c.Compute.BastionMode = other.Compute.BastionMode
+	}
+	if other.Compute.BastionAllowedCidrs != nil {
+		c.Compute.BastionAllowedCidrs = other.Compute.BastionAllowedCidrs
+	}
+	mergeBool(&c.Compute.InstanceConnectEndpoint, other.Compute.InstanceConnectEndpoint)
+	mergeNumber(&c.Compute.MinCapacity, other.Compute.MinCapacity)
+	mergeNumber(&c.Compute.MaxCapacity, other.Compute.MaxCapacity)
+	mergeNumber(&c.Compute.DesiredCapacity, other.Compute.DesiredCapacity)
+
+	mergeNumber(&c.Lambda.MemorySize, other.Lambda.MemorySize)
+	mergeNumber(&c.Lambda.TimeoutSeconds, other.Lambda.TimeoutSeconds)
+
+	if other.Cdn.PriceClass != "" {
+		c.Cdn.PriceClass = other.Cdn.PriceClass
+	}
+	if other.Cdn.BlockedCidrs != nil {
+		c.Cdn.BlockedCidrs = other.Cdn.BlockedCidrs
+	}
+	if other.Logging.Retention != "" {
+		c.Logging.Retention = other.Logging.Retention
+	}
+
+	mergeNumber(&c.SecretRotationDays, other.SecretRotationDays)
+	if other.RemovalPolicy != "" {
+		c.RemovalPolicy = other.RemovalPolicy
+	}
+}
+
+// Validate rejects impossible or unsupported combinations of settings.
+func (c StackConfig) Validate() error {
+	var errs []error
+
+	// Network
+	vpcPrefix := 0
+	if c.Network.VpcCidr == nil {
+		errs = append(errs, errors.New("network: VpcCidr is required"))
+	} else if ip, ipNet, err := net.ParseCIDR(*c.Network.VpcCidr); err != nil || ip.To4() == nil {
+		errs = append(errs, fmt.Errorf("network: VpcCidr %q is not a valid IPv4 CIDR block", *c.Network.VpcCidr))
+	} else {
+		vpcPrefix, _ = ipNet.Mask.Size()
+		if vpcPrefix < 16 || vpcPrefix > 28 {
+			errs = append(errs, fmt.Errorf("network: VpcCidr prefix /%d must be between /16 and /28", vpcPrefix))
+		}
+	}
+	if !isWholeNumber(c.Network.MaxAzs) || *c.Network.MaxAzs < 2 {
+		errs = append(errs, errors.New("network: MaxAzs must be a whole number of at least 2 (the ALB spans two Availability Zones)"))
+	}
+	if !isWholeNumber(c.Network.SubnetCidrMask) || *c.Network.SubnetCidrMask < 16 || *c.Network.SubnetCidrMask > 28 {
+		errs = append(errs, errors.New("network: SubnetCidrMask must be a whole number between 16 and 28"))
+	} else if vpcPrefix != 0 && isWholeNumber(c.Network.MaxAzs) {
+		mask := int(*c.Network.SubnetCidrMask)
+		subnets := subnetTierCount * int(*c.Network.MaxAzs)
+		if mask <= vpcPrefix {
+			errs = append(errs, fmt.Errorf("network: SubnetCidrMask /%d must be longer than the VPC prefix /%d", mask, vpcPrefix))
+		} else if capacity := 1 << (mask - vpcPrefix); subnets > capacity {
+			errs = append(errs, fmt.Errorf("network: VpcCidr %s fits %d /%d subnets but %d Availability Zones need %d",
+				*c.Network.VpcCidr, capacity, mask, int(*c.Network.MaxAzs), subnets))
+		} else if c.Network.NetworkFirewall != nil && *c.Network.NetworkFirewall {
+			free := c.Network.smallSubnetRoom()
+			if needed := firewallSubnetTierCount * int(*c.Network.MaxAzs); needed > free {
+				errs = append(errs, fmt.Errorf("network: VpcCidr %s has room for %d /%d subnets after the other tiers but NetworkFirewall needs %d",
+					*c.Network.VpcCidr, free, FirewallSubnetCidrMask, needed))
+			}
+		}
+	}
+
+	if c.Network.NatGateways != nil {
+		if !isWholeNumber(c.Network.NatGateways) || *c.Network.NatGateways < 1 {
+			errs = append(errs, errors.New("network: NatGateways must be a whole number of at least 1"))
+		} else if isWholeNumber(c.Network.MaxAzs) && *c.Network.NatGateways > *c.Network.MaxAzs {
+			errs = append(errs, fmt.Errorf("network: NatGateways %v exceeds MaxAzs %v", *c.Network.NatGateways, *c.Network.MaxAzs))
+		}
+	}
+	switch c.Network.NatMode {
+	case NatModePerAz:
+		// NatGateways is checked above
+	case NatModeSingle:
+		if c.Network.NatGateways != nil && *c.Network.NatGateways != 1 {
+			errs = append(errs, errors.New("network: NatMode single uses one NAT gateway, NatGateways must be unset or 1"))
+		}
+	case NatModeInstance:
+		if !isInstanceType(c.Network.NatInstanceType) || !gravitonFamilyPattern.MatchString(*c.Network.NatInstanceType) {
+			errs = append(errs, errors.New(`network: NatInstanceType must be a Graviton instance type like "t4g.nano"`))
+		}
+	case NatModeNone:
+		if c.Network.NatGateways != nil {
+			errs = append(errs, errors.New("network: NatMode none cannot be combined with NatGateways"))
+		}
+		if c.Network.VpcEndpoints == nil || !*c.Network.VpcEndpoints {
+			errs = append(errs, errors.New("network: NatMode none requires VpcEndpoints so private workloads can reach AWS APIs"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("network: unsupported NatMode %q", c.Network.NatMode))
+	}
+
+	switch c.Network.ResolverQueryLogs {
+	case QueryLogDestinationNone, QueryLogDestinationS3, QueryLogDestinationCloudWatch:
+	default:
+		errs = append(errs, fmt.Errorf("network: unsupported ResolverQueryLogs %q", c.Network.ResolverQueryLogs))
+	}
+	if c.Network.DnsFirewall != nil && *c.Network.DnsFirewall {
+		if len(c.Network.DnsFirewallBlockedDomains) == 0 {
+			errs = append(errs, errors.New("network: DnsFirewall requires at least one DnsFirewallBlockedDomains entry"))
+		}
+		for _, domain := range append(append([]string{}, c.Network.DnsFirewallBlockedDomains...), c.Network.DnsFirewallAllowedDomains...) {
+			if !firewallDomainPattern.MatchString(domain) {
+				errs = append(errs, fmt.Errorf(`network: DNS Firewall domain %q must be a lower-case domain name, optionally starting with "*."`, domain))
+			}
+		}
+	} else if len(c.Network.DnsFirewallBlockedDomains) > 0 || len(c.Network.DnsFirewallAllowedDomains) > 0 {
+		errs = append(errs, errors.New("network: DnsFirewallBlockedDomains and DnsFirewallAllowedDomains require DnsFirewall"))
+	}
+	if c.Network.NetworkFirewall != nil && *c.Network.NetworkFirewall {
+		if c.Network.NatMode != NatModePerAz && c.Network.NatMode != NatModeSingle {
+			errs = append(errs, errors.New("network: NetworkFirewall inspects NAT gateway egress, NatMode must be per-az or single"))
+		}
+		if c.Network.DualStack != nil && *c.Network.DualStack {
+			errs = append(errs, errors.New("network: NetworkFirewall does not inspect IPv6 egress and cannot be combined with DualStack"))
+		}
+		domains := len(c.Network.NetworkFirewallAllowedDomains)
+		for _, rule := range DefaultFirewallDomainRules {
+			domains += len(rule.Domains)
+		}
+		if domains > maxFirewallDomains {
+			errs = append(errs, fmt.Errorf("network: the Network Firewall allows at most %d domains, got %d", maxFirewallDomains, domains))
+		}
+		for _, domain := range c.Network.NetworkFirewallAllowedDomains {
+			if !networkFirewallDomainPattern.MatchString(domain) {
+				errs = append(errs, fmt.Errorf(`network: NetworkFirewallAllowedDomains entry %q must be a lower-case domain name, optionally starting with "."`, domain))
+			}
+		}
+	} else if len(c.Network.NetworkFirewallAllowedDomains) > 0 {
+		errs = append(errs, errors.New("network: NetworkFirewallAllowedDomains requires NetworkFirewall"))
+	}
+
+	// Compute
+	if !isInstanceType(c.Compute.InstanceType) {
+		errs = append(errs, errors.New(`compute: InstanceType must look like "t3.micro"`))
+	}
+	if !isInstanceType(c.Compute.BastionInstanceType) {
+		errs = append(errs, errors.New(`compute: BastionInstanceType must look like "t3.nano"`))
+	}
+	switch c.Compute.BastionMode {
+	case BastionModeSsm, BastionModeNone:
+		if len(c.Compute.BastionAllowedCidrs) > 0 {
+			errs = append(errs, fmt.Errorf("compute: BastionAllowedCidrs only applies to BastionMode %s", BastionModeCidrAllowlist))
+		}
+	case BastionModeCidrAllowlist:
+		if len(c.Compute.BastionAllowedCidrs) == 0 {
+			errs = append(errs, errors.New("compute: BastionMode cidr-allowlist requires at least one BastionAllowedCidrs entry"))
+		}
+		for _, cidr := range c.Compute.BastionAllowedCidrs {
+			if _, ipNet, err := net.ParseCIDR(cidr); err != nil {
+				errs = append(errs, fmt.Errorf("compute: BastionAllowedCidrs entry %q is not a valid CIDR block", cidr))
+			} else if ones, _ := ipNet.Mask.Size(); ones == 0 {
+				errs = append(errs, fmt.Errorf("compute: BastionAllowedCidrs entry %q would open SSH to the internet", cidr))
+			}
+		}
+	default:
+		errs = append(errs, fmt.Errorf("compute: unsupported BastionMode %q", c.Compute.BastionMode))
+	}
+	capacities := []*float64{c.Compute.MinCapacity, c.Compute.MaxCapacity, c.Compute.DesiredCapacity}
+	if !isWholeNumber(capacities...) {
+		errs = append(errs, errors.New("compute: MinCapacity, MaxCapacity and DesiredCapacity must be whole numbers"))
+	} else {
+		minCapacity, maxCapacity, desired := *c.Compute.MinCapacity, *c.Compute.MaxCapacity, *c.Compute.DesiredCapacity
+		if minCapacity < 0 || maxCapacity < 1 {
+			errs = append(errs, errors.New("compute: MinCapacity must be >= 0 and MaxCapacity >= 1"))
+		}
+		if minCapacity > maxCapacity {
+			errs = append(errs, fmt.Errorf("compute: MinCapacity %v exceeds MaxCapacity %v", minCapacity, maxCapacity))
+		}
+		if desired < minCapacity || desired > maxCapacity {
+			errs = append(errs, fmt.Errorf("compute: DesiredCapacity %v must be between MinCapacity %v and MaxCapacity %v", desired, minCapacity, maxCapacity))
+		}
+	}
+
+	// Lambda
+	if !isWholeNumber(c.Lambda.MemorySize) || *c.Lambda.MemorySize < 128 || *c.Lambda.MemorySize > 10240 {
+		errs = append(errs, errors.New("lambda: MemorySize must be a whole number of MB between 128 and 10240"))
+	}
+	if !isWholeNumber(c.Lambda.TimeoutSeconds) || *c.Lambda.TimeoutSeconds < 1 || *c.Lambda.TimeoutSeconds > 900 {
+		errs = append(errs, errors.New("lambda: TimeoutSeconds must be a whole number between 1 and 900"))
+	}
+
+	// CDN
+	if !validPriceClasses[c.Cdn.PriceClass] {
+		errs = append(errs, fmt.Errorf("cdn: unsupported PriceClass %q", c.Cdn.PriceClass))
+	}
+	if err := validateBlockedCidrs(c.Cdn.BlockedCidrs); err != nil {
+		errs = append(errs, fmt.Errorf("cdn: %w", err))
+	}
+
+	// Logging
+	if !validRetentions[c.Logging.Retention] {
+		errs = append(errs, fmt.Errorf("logging: unsupported Retention %q", c.Logging.Retention))
+	}
+
+	if !isWholeNumber(c.SecretRotationDays) || *c.SecretRotationDays < 1 || *c.SecretRotationDays > 1000 {
+		errs = append(errs, errors.New("SecretRotationDays must be a whole number between 1 and 1000"))
+	}
+
+	if c.RemovalPolicy != awscdk.RemovalPolicy_DESTROY && c.RemovalPolicy != awscdk.RemovalPolicy_RETAIN {
+		errs = append(errs, fmt.Errorf("RemovalPolicy must be DESTROY or RETAIN, got %q", c.RemovalPolicy))
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateBlockedCidrs checks the ranges blocked by the WAF WebACL
+func validateBlockedCidrs(cidrs []string) error {
+	var errs []error
+	for _, cidr := range cidrs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			errs = append(errs, fmt.Errorf("BlockedCidrs entry %q is not a valid CIDR block", cidr))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// smallSubnetRoom returns how many /28 subnets, as used by the firewall, NAT
+// and Transit Gateway tiers, fit in the VPC CIDR after the public, private and
+// isolated tiers. It returns -1 when the sizing itself is invalid.
+func (c NetworkConfig) smallSubnetRoom() int {
+	if c.VpcCidr == nil || !isWholeNumber(c.MaxAzs, c.SubnetCidrMask) {
+		return -1
+	}
+	ip, ipNet, err := net.ParseCIDR(*c.VpcCidr)
+	if err != nil || ip.To4() == nil {
+		return -1
+	}
+	vpcPrefix, _ := ipNet.Mask.Size()
+	mask := int(*c.SubnetCidrMask)
+	if mask <= vpcPrefix || mask > FirewallSubnetCidrMask {
+		return -1
+	}
+	free := 1<<(mask-vpcPrefix) - subnetTierCount*int(*c.MaxAzs)
+	if free < 0 {
+		return -1
+	}
+	return free << (FirewallSubnetCidrMask - mask)
+}
+
+// derefOrZero returns the value behind an optional sub-struct
+func derefOrZero[T any](value *T) T {
+	if value == nil {
+		var zero T
+		return zero
+	}
+	return *value
+}
+
+func mergeString(dst **string, src *string) {
+	if src != nil {
+		*dst = src
+	}
+}
+
+func mergeNumber(dst **float64, src *float64) {
+	if src != nil {
+		*dst = src
+	}
+}
+
+func mergeBool(dst **bool, src *bool) {
+	if src != nil {
+		*dst = src
+	}
+}
+
+// isInstanceType reports whether the value is set and looks like an EC2 instance type
+func isInstanceType(value *string) bool {
+	return value != nil && instanceTypePattern.MatchString(*value)
+}
+
+// isWholeNumber reports whether every value is set and has no fractional part
+func isWholeNumber(values ...*float64) bool {
+	for _, value := range values {
+		if value == nil || *value != float64(int(*value)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,116 @@
+package lib
+
+import (
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awscloudfront"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsec2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsrds"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsroute53"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsroute53targets"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// DnsConstructProps defines the inputs of the DnsConstruct.
+type DnsConstructProps struct {
+	// Namer builds resource names.
+	Namer Namer
+	// Vpc is associated with the private hosted zone.
+	Vpc awsec2.IVpc
+	// PrivateZoneName is the private hosted zone, e.g. "dev.internal".
+	PrivateZoneName *string
+	// Database gets the "db" (writer) and "db-ro" (reader) records when set.
+	Database awsrds.IDatabaseCluster
+	// PublicZoneName creates a public hosted zone. When the distribution is
+	// served on CdnDomainName, which must be inside the zone, alias records
+	// point the domain at CloudFront.
+	PublicZoneName *string
+	Distribution   awscloudfront.IDistribution
+	CdnDomainName  *string
+	// DualStack adds an AAAA alias record next to the CloudFront A record.
+	DualStack bool
+}
+
+// DnsConstruct holds the private hosted zone with the service names of the
+// stack and the optional public hosted zone of the custom CloudFront domain.
+// The ALB has no private name: it is internet-facing, so resolving it from
+// inside the VPC would send callers out through NAT to its public addresses.
+type DnsConstruct struct {
+	constructs.Construct
+	PrivateZone awsroute53.PrivateHostedZone
+	// PublicZone is nil unless PublicZoneName is set.
+	PublicZone awsroute53.PublicHostedZone
+	// Records is keyed by record name relative to the private zone: db and db-ro.
+	Records map[string]awsroute53.RecordSet
+}
+
+// NewDnsConstruct creates the hosted zones and their records.
+func NewDnsConstruct(scope constructs.Construct, id *string, props *DnsConstructProps) *DnsConstruct {
+	dns := &DnsConstruct{
+		Construct: constructs.NewConstruct(scope, id),
+		Records:   make(map[string]awsroute53.RecordSet),
+	}
+
+	dns.createPrivateZone(props)
+	dns.createPublicZone(props)
+
+	return dns
+}
+
+// createPrivateZone creates the VPC-only zone with a stable name for each service
+func (d *DnsConstruct) createPrivateZone(props *DnsConstructProps) {
+	d.PrivateZone = awsroute53.NewPrivateHostedZone(d.Construct, jsii.String("PrivateZone"), &awsroute53.PrivateHostedZoneProps{
+		ZoneName: props.PrivateZoneName,
+		Vpc:      props.Vpc,
+		Comment:  jsii.String("Service names of " + props.Namer.Prefix()),
+	})
+
+	// Cluster endpoints keep their hostnames across failovers, so CNAMEs are enough
+	if props.Database != nil {
+		d.Records["db"] = awsroute53.NewCnameRecord(d.Construct, jsii.String("DatabaseRecord"), &awsroute53.CnameRecordProps{
+			Zone:       d.PrivateZone,
+			RecordName: jsii.String("db"),
+			DomainName: props.Database.ClusterEndpoint().Hostname(),
+			Ttl:        awscdk.Duration_Minutes(jsii.Number(5)),
+		})
+		d.Records["db-ro"] = awsroute53.NewCnameRecord(d.Construct, jsii.String("DatabaseReaderRecord"), &awsroute53.CnameRecordProps{
+			Zone:       d.PrivateZone,
+			RecordName: jsii.String("db-ro"),
+			DomainName: props.Database.ClusterReadEndpoint().Hostname(),
+			Ttl:        awscdk.Duration_Minutes(jsii.Number(5)),
+		})
+	}
+
+	awscdk.Tags_Of(d.PrivateZone).Add(jsii.String("Name"), resourceName(props.Namer, ResourceGeneric, "private-zone"), nil)
+}
+
+// createPublicZone creates the public zone and points the custom CloudFront domain at the distribution
+func (d *DnsConstruct) createPublicZone(props *DnsConstructProps) {
+	if props.PublicZoneName == nil {
+		return
+	}
+
+	d.PublicZone = awsroute53.NewPublicHostedZone(d.Construct, jsii.String("PublicZone"), &awsroute53.PublicHostedZoneProps{
+		ZoneName: props.PublicZoneName,
+		Comment:  jsii.String("Public zone of " + props.Namer.Prefix()),
+	})
+	awscdk.Tags_Of(d.PublicZone).Add(jsii.String("Name"), resourceName(props.Namer, ResourceGeneric, "public-zone"), nil)
+
+	if props.CdnDomainName == nil {
+		return
+	}
+
+	cdnTarget := awsroute53.RecordTarget_FromAlias(awsroute53targets.NewCloudFrontTarget(props.Distribution))
+	awsroute53.NewARecord(d.Construct, jsii.String("CdnRecord"), &awsroute53.ARecordProps{
+		Zone:       d.PublicZone,
+		RecordName: props.CdnDomainName,
+		Target:     cdnTarget,
+	})
+	if props.DualStack {
+		awsroute53.NewAaaaRecord(d.Construct, jsii.String("CdnIpv6Record"), &awsroute53.AaaaRecordProps{
+			Zone:       d.PublicZone,
+			RecordName: props.CdnDomainName,
+			Target:     cdnTarget,
+		})
+	}
+}
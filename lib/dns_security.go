@@ -0,0 +1,111 @@
+package lib
+
+import (
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awscloudwatch"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awscloudwatchactions"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslogs"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsroute53resolver"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// queryLogsPrefix is the logging bucket prefix Resolver query logs are written under.
+const queryLogsPrefix = "resolver-query-logs"
+
+// createResolverQueryLogging records the DNS queries made from the VPC, which
+// flow logs do not capture
+func (o *ObservabilityConstruct) createResolverQueryLogging(props *ObservabilityConstructProps) {
+	var destinationArn *string
+	switch props.ResolverQueryLogs {
+	case QueryLogDestinationS3:
+		destinationArn = jsii.String(*props.LogBucket.BucketArn() + "/" + queryLogsPrefix)
+		allowLogDelivery(props.LogBucket, queryLogsPrefix)
+	case QueryLogDestinationCloudWatch:
+		o.QueryLogGroup = awslogs.NewLogGroup(o.Construct, jsii.String("ResolverQueryLogGroup"), &awslogs.LogGroupProps{
+			LogGroupName:  jsii.String("/aws/route53resolver/" + props.Namer.Name(ResourceGeneric, "query-logs")),
+			EncryptionKey: props.KmsKey,
+			Retention:     props.LogRetention,
+			RemovalPolicy: props.RemovalPolicy,
+		})
+		destinationArn = o.QueryLogGroup.LogGroupArn()
+	default:
+		return
+	}
+
+	o.QueryLogConfig = awsroute53resolver.NewCfnResolverQueryLoggingConfig(o.Construct, jsii.String("ResolverQueryLogConfig"), &awsroute53resolver.CfnResolverQueryLoggingConfigProps{
+		Name:           resourceName(props.Namer, ResourceGeneric, "query-logs"),
+		DestinationArn: destinationArn,
+	})
+	awsroute53resolver.NewCfnResolverQueryLoggingConfigAssociation(o.Construct, jsii.String("ResolverQueryLogAssociation"), &awsroute53resolver.CfnResolverQueryLoggingConfigAssociationProps{
+		ResolverQueryLogConfigId: o.QueryLogConfig.AttrId(),
+		ResourceId:               props.Vpc.VpcId(),
+	})
+}
+
+// createDnsFirewall associates a DNS Firewall rule group with the VPC and
+// alarms when it blocks a query
+func (o *ObservabilityConstruct) createDnsFirewall(props *ObservabilityConstructProps) {
+	if !props.DnsFirewall {
+		return
+	}
+
+	blocked := awsroute53resolver.NewCfnFirewallDomainList(o.Construct, jsii.String("DnsFirewallBlockedDomains"), &awsroute53resolver.CfnFirewallDomainListProps{
+		Name:    resourceName(props.Namer, ResourceGeneric, "dns-blocked-domains"),
+		Domains: jsii.Strings(props.DnsFirewallBlockedDomains...),
+	})
+	rules := []interface{}{
+		&awsroute53resolver.CfnFirewallRuleGroup_FirewallRuleProperty{
+			Action:               jsii.String("BLOCK"),
+			BlockResponse:        jsii.String("NODATA"),
+			FirewallDomainListId: blocked.AttrId(),
+			Priority:             jsii.Number(200),
+		},
+	}
+
+	// Allowed domains are evaluated first so they win over a broader block
+	if len(props.DnsFirewallAllowedDomains) > 0 {
+		allowed := awsroute53resolver.NewCfnFirewallDomainList(o.Construct, jsii.String("DnsFirewallAllowedDomains"), &awsroute53resolver.CfnFirewallDomainListProps{
+			Name:    resourceName(props.Namer, ResourceGeneric, "dns-allowed-domains"),
+			Domains: jsii.Strings(props.DnsFirewallAllowedDomains...),
+		})
+		rules = append(rules, &awsroute53resolver.CfnFirewallRuleGroup_FirewallRuleProperty{
+			Action:               jsii.String("ALLOW"),
+			FirewallDomainListId: allowed.AttrId(),
+			Priority:             jsii.Number(100),
+		})
+	}
+
+	o.DnsFirewallRuleGroup = awsroute53resolver.NewCfnFirewallRuleGroup(o.Construct, jsii.String("DnsFirewallRuleGroup"), &awsroute53resolver.CfnFirewallRuleGroupProps{
+		Name:          resourceName(props.Namer, ResourceGeneric, "dns-firewall"),
+		FirewallRules: &rules,
+	})
+	awsroute53resolver.NewCfnFirewallRuleGroupAssociation(o.Construct, jsii.String("DnsFirewallAssociation"), &awsroute53resolver.CfnFirewallRuleGroupAssociationProps{
+		Name:                resourceName(props.Namer, ResourceGeneric, "dns-firewall"),
+		FirewallRuleGroupId: o.DnsFirewallRuleGroup.AttrId(),
+		VpcId:               props.Vpc.VpcId(),
+		Priority:            jsii.Number(1000),
+	})
+
+	blockedQueries := awscloudwatch.NewMetric(&awscloudwatch.MetricProps{
+		Namespace:  jsii.String("AWS/Route53Resolver"),
+		MetricName: jsii.String("FirewallRuleQueryVolume"),
+		DimensionsMap: &map[string]*string{
+			"FirewallRuleGroupId":  o.DnsFirewallRuleGroup.AttrId(),
+			"FirewallDomainListId": blocked.AttrId(),
+		},
+		Statistic: jsii.String("Sum"),
+		Period:    awscdk.Duration_Minutes(jsii.Number(5)),
+	})
+	o.DnsFirewallAlarm = awscloudwatch.NewAlarm(o.Construct, jsii.String("DnsFirewallBlockedAlarm"), &awscloudwatch.AlarmProps{
+		AlarmName:          resourceName(props.Namer, ResourceGeneric, "dns-firewall-blocked"),
+		AlarmDescription:   jsii.String("DNS Firewall blocked a query from the VPC"),
+		Metric:             blockedQueries,
+		Threshold:          jsii.Number(1),
+		EvaluationPeriods:  jsii.Number(1),
+		ComparisonOperator: awscloudwatch.ComparisonOperator_GREATER_THAN_OR_EQUAL_TO_THRESHOLD,
+		TreatMissingData:   awscloudwatch.TreatMissingData_NOT_BREACHING,
+	})
+	o.DnsFirewallAlarm.AddAlarmAction(awscloudwatchactions.NewSnsAction(o.AlertsTopic))
+
+	awscdk.Tags_Of(o.DnsFirewallRuleGroup).Add(jsii.String("Name"), resourceName(props.Namer, ResourceGeneric, "dns-firewall"), nil)
+}
@@ -0,0 +1,108 @@
+package lib
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awscertificatemanager"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awscloudfront"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awscloudfrontorigins"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awss3"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awswafv2"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// EdgeConstructProps defines the inputs of the EdgeConstruct.
+type EdgeConstructProps struct {
+	// Namer builds resource names.
+	Namer Namer
+	// OriginBucket is served through the distribution.
+	OriginBucket awss3.IBucket
+	// LogBucket receives the CloudFront access logs.
+	LogBucket  awss3.IBucket
+	PriceClass awscloudfront.PriceClass
+	// WebACL is a CLOUDFRONT-scoped WebACL created elsewhere (usually the
+	// EdgeStack). When nil one is created here, which requires us-east-1.
+	WebACL awswafv2.CfnWebACL
+	// BlockedCidrs are blocked by the WebACL created here. Ignored when
+	// WebACL is provided.
+	BlockedCidrs []string
+	// Certificate and DomainName serve the distribution on a custom domain.
+	Certificate awscertificatemanager.ICertificate
+	DomainName  *string
+	// EnableIpv6 serves the distribution over IPv6 as well.
+	EnableIpv6 bool
+}
+
+// EdgeConstruct is the WAF-protected CloudFront distribution in front of the
+// application bucket.
+type EdgeConstruct struct {
+	constructs.Construct
+	WAF                  awswafv2.CfnWebACL
+	OriginAccessIdentity awscloudfront.OriginAccessIdentity
+	Distribution         awscloudfront.Distribution
+}
+
+// NewEdgeConstruct creates the WebACL (unless provided) and the distribution.
+func NewEdgeConstruct(scope constructs.Construct, id *string, props *EdgeConstructProps) *EdgeConstruct {
+	edge := &EdgeConstruct{
+		Construct: constructs.NewConstruct(scope, id),
+	}
+
+	edge.createWAF(props)
+	edge.createCloudFront(props)
+
+	return edge
+}
+
+// createWAF creates Web Application Firewall (attached to CloudFront in createCloudFront).
+// When a WebACL is provided (e.g. by the us-east-1 edge stack) it is referenced instead.
+func (e *EdgeConstruct) createWAF(props *EdgeConstructProps) {
+	if props.WebACL != nil {
+		e.WAF = props.WebACL
+		return
+	}
+
+	e.WAF = newCloudFrontWebACL(e.Construct, jsii.String("ProdWAF"), props.Namer, props.BlockedCidrs)
+}
+
+// createCloudFront creates CloudFront distribution with WAF
+func (e *EdgeConstruct) createCloudFront(props *EdgeConstructProps) {
+	// Create Origin Access Identity for CloudFront (still needed for compatibility)
+	e.OriginAccessIdentity = awscloudfront.NewOriginAccessIdentity(e.Construct, jsii.String("ProdCloudFrontOAI"), &awscloudfront.OriginAccessIdentityProps{
+		Comment: jsii.String(fmt.Sprintf("OAI for %s S3 bucket", props.Namer.Prefix())),
+	})
+
+	// Grant CloudFront OAI read access to S3 bucket
+	props.OriginBucket.GrantRead(e.OriginAccessIdentity.GrantPrincipal(), jsii.String("*"))
+
+	// Serve the custom domain when a certificate was issued for it
+	var domainNames *[]*string
+	if props.Certificate != nil && props.DomainName != nil {
+		domainNames = &[]*string{props.DomainName}
+	}
+
+	// Create CloudFront distribution using S3Origin (deprecated but still functional)
+	e.Distribution = awscloudfront.NewDistribution(e.Construct, jsii.String("ProdCloudFrontDist"), &awscloudfront.DistributionProps{
+		Certificate: props.Certificate,
+		DomainNames: domainNames,
+		Comment:     jsii.String(fmt.Sprintf("CloudFront distribution for %s", props.Namer.Prefix())),
+		DefaultBehavior: &awscloudfront.BehaviorOptions{
+			Origin: awscloudfrontorigins.NewS3Origin(props.OriginBucket, &awscloudfrontorigins.S3OriginProps{
+				OriginAccessIdentity: e.OriginAccessIdentity,
+			}),
+			ViewerProtocolPolicy: awscloudfront.ViewerProtocolPolicy_REDIRECT_TO_HTTPS,
+			CachePolicy:          awscloudfront.CachePolicy_CACHING_OPTIMIZED(),
+		},
+		WebAclId:           e.WAF.AttrArn(),
+		PriceClass:         props.PriceClass,
+		EnableIpv6:         jsii.Bool(props.EnableIpv6),
+		EnableLogging:      jsii.Bool(true),
+		LogBucket:          props.LogBucket,
+		LogFilePrefix:      jsii.String(cloudFrontLogsPrefix + "/"),
+		LogIncludesCookies: jsii.Bool(false),
+	})
+
+	awscdk.Tags_Of(e.Distribution).Add(jsii.String("Name"), resourceName(props.Namer, ResourceGeneric, "cloudfront"), nil)
+}
@@ -0,0 +1,211 @@
+package lib
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awscertificatemanager"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awswafv2"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// EdgeRegion is the only region where CLOUDFRONT-scoped WAF ACLs and
+// CloudFront ACM certificates can be created.
+const EdgeRegion = "us-east-1"
+
+// EdgeStackProps defines the properties for the EdgeStack CDK stack.
+type EdgeStackProps struct {
+	*awscdk.StackProps
+	EnvironmentSuffix *string
+	// CdnDomainName is the custom CloudFront domain. When set, a DNS-validated
+	// ACM certificate is issued for it in us-east-1.
+	CdnDomainName *string
+	// BlockedCidrs are IPv4 and IPv6 ranges the WebACL blocks, usually the
+	// workload's CdnConfig.BlockedCidrs.
+	BlockedCidrs []string
+	// Namer builds resource names. Defaults to NewDefaultNamer(EnvironmentSuffix).
+	Namer Namer
+}
+
+// EdgeStack holds the us-east-1-only resources consumed by TapStack through
+// cross-region references.
+type EdgeStack struct {
+	awscdk.Stack
+	EnvironmentSuffix *string
+	CdnDomainName     *string
+	Namer             Namer
+	WAF               awswafv2.CfnWebACL
+	Certificate       awscertificatemanager.Certificate
+}
+
+// NewEdgeStack creates the edge stack pinned to us-east-1. It panics when the
+// environment suffix is invalid; use NewEdgeStackE to get the error instead.
+func NewEdgeStack(scope constructs.Construct, id *string, props *EdgeStackProps) *EdgeStack {
+	edgeStack, err := NewEdgeStackE(scope, id, props)
+	if err != nil {
+		panic(err.Error())
+	}
+	return edgeStack
+}
+
+// NewEdgeStackE creates the edge stack like NewEdgeStack, but returns a
+// descriptive error instead of panicking when the props are invalid.
+func NewEdgeStackE(scope constructs.Construct, id *string, props *EdgeStackProps) (*EdgeStack, error) {
+	var sprops awscdk.StackProps
+	if props != nil && props.StackProps != nil {
+		sprops = *props.StackProps
+	}
+
+	// Keep the caller's account but always deploy to the edge region
+	env := awscdk.Environment{}
+	if sprops.Env != nil {
+		env = *sprops.Env
+	}
+	env.Region = jsii.String(EdgeRegion)
+	sprops.Env = &env
+	sprops.CrossRegionReferences = jsii.Bool(true)
+
+	var explicitSuffix *string
+	if props != nil {
+		explicitSuffix = props.EnvironmentSuffix
+	}
+	environmentSuffix, err := ResolveEnvironmentSuffix(scope, explicitSuffix)
+	if err != nil {
+		return nil, fmt.Errorf("invalid EdgeStackProps: %w", err)
+	}
+	var blockedCidrs []string
+	if props != nil {
+		blockedCidrs = props.BlockedCidrs
+	}
+	if err := validateBlockedCidrs(blockedCidrs); err != nil {
+		return nil, fmt.Errorf("invalid EdgeStackProps: %w", err)
+	}
+
+	stack := awscdk.NewStack(scope, id, &sprops)
+
+	var namer Namer = NewDefaultNamer(environmentSuffix)
+	if props != nil && props.Namer != nil {
+		namer = props.Namer
+	}
+
+	awscdk.Tags_Of(stack).Add(jsii.String("Environment"), jsii.String(environmentSuffix), nil)
+	awscdk.Tags_Of(stack).Add(jsii.String("Project"), jsii.String(namer.Prefix()), nil)
+	awscdk.Tags_Of(stack).Add(jsii.String("ManagedBy"), jsii.String("CDK"), nil)
+
+	edgeStack := &EdgeStack{
+		Stack:             stack,
+		EnvironmentSuffix: jsii.String(environmentSuffix),
+		Namer:             namer,
+	}
+	if props != nil {
+		edgeStack.CdnDomainName = props.CdnDomainName
+	}
+
+	edgeStack.WAF = newCloudFrontWebACL(stack, jsii.String("ProdWAF"), namer, blockedCidrs)
+	edgeStack.createCertificate()
+
+	return edgeStack, nil
+}
+
+// createCertificate creates the CloudFront ACM certificate for the custom domain
+func (e *EdgeStack) createCertificate() {
+	if e.CdnDomainName == nil {
+		return
+	}
+
+	e.Certificate = awscertificatemanager.NewCertificate(e.Stack, jsii.String("ProdCloudFrontCertificate"), &awscertificatemanager.CertificateProps{
+		DomainName:      e.CdnDomainName,
+		CertificateName: jsii.String(e.Namer.Name(ResourceGeneric, "cloudfront-cert")),
+		Validation:      awscertificatemanager.CertificateValidation_FromDns(nil),
+	})
+
+	awscdk.Tags_Of(e.Certificate).Add(jsii.String("Name"), jsii.String(e.Namer.Name(ResourceGeneric, "cloudfront-cert")), nil)
+}
+
+// newCloudFrontWebACL creates the CLOUDFRONT-scoped WAF v2 Web ACL. With
+// blockedCidrs, requests from those ranges are blocked before any other rule.
+func newCloudFrontWebACL(scope constructs.Construct, id *string, namer Namer, blockedCidrs []string) awswafv2.CfnWebACL {
+	name := jsii.String(namer.Name(ResourceGeneric, "waf"))
+	rules := []interface{}{
+		&awswafv2.CfnWebACL_RuleProperty{
+			Name:     jsii.String("AWSManagedRulesCommonRuleSet"),
+			Priority: jsii.Number(1),
+			Statement: &awswafv2.CfnWebACL_StatementProperty{
+				ManagedRuleGroupStatement: &awswafv2.CfnWebACL_ManagedRuleGroupStatementProperty{
+					VendorName: jsii.String("AWS"),
+					Name:       jsii.String("AWSManagedRulesCommonRuleSet"),
+				},
+			},
+			OverrideAction: &awswafv2.CfnWebACL_OverrideActionProperty{
+				None: &map[string]interface{}{},
+			},
+			VisibilityConfig: &awswafv2.CfnWebACL_VisibilityConfigProperty{
+				SampledRequestsEnabled:   jsii.Bool(true),
+				CloudWatchMetricsEnabled: jsii.Bool(true),
+				MetricName:               jsii.String("CommonRuleSetMetric"),
+			},
+		},
+	}
+	if len(blockedCidrs) > 0 {
+		rules = append([]interface{}{newBlockedCidrsRule(scope, namer, blockedCidrs)}, rules...)
+	}
+
+	return awswafv2.NewCfnWebACL(scope, id, &awswafv2.CfnWebACLProps{
+		Name:  name,
+		Scope: jsii.String("CLOUDFRONT"),
+		DefaultAction: &awswafv2.CfnWebACL_DefaultActionProperty{
+			Allow: &awswafv2.CfnWebACL_AllowActionProperty{},
+		},
+		Rules: &rules,
+		VisibilityConfig: &awswafv2.CfnWebACL_VisibilityConfigProperty{
+			SampledRequestsEnabled:   jsii.Bool(true),
+			CloudWatchMetricsEnabled: jsii.Bool(true),
+			MetricName:               name,
+		},
+	})
+}
+
+// newBlockedCidrsRule creates one IP set per address family, so IPv6 clients
+// of a dual-stack distribution are matched too, and a rule blocking both
+func newBlockedCidrsRule(scope constructs.Construct, namer Namer, cidrs []string) *awswafv2.CfnWebACL_RuleProperty {
+	addresses := map[string][]*string{"IPV4": {}, "IPV6": {}}
+	for _, cidr := range cidrs {
+		version := "IPV4"
+		if strings.Contains(cidr, ":") {
+			version = "IPV6"
+		}
+		addresses[version] = append(addresses[version], jsii.String(cidr))
+	}
+
+	var statements []interface{}
+	for _, set := range []struct{ id, version string }{{"BlockedIpv4Set", "IPV4"}, {"BlockedIpv6Set", "IPV6"}} {
+		list := addresses[set.version]
+		ipSet := awswafv2.NewCfnIPSet(scope, jsii.String(set.id), &awswafv2.CfnIPSetProps{
+			Name:             jsii.String(namer.Name(ResourceGeneric, "waf-blocked-"+strings.ToLower(set.version))),
+			Scope:            jsii.String("CLOUDFRONT"),
+			IpAddressVersion: jsii.String(set.version),
+			Addresses:        &list,
+		})
+		statements = append(statements, &awswafv2.CfnWebACL_StatementProperty{
+			IpSetReferenceStatement: &awswafv2.CfnWebACL_IPSetReferenceStatementProperty{Arn: ipSet.AttrArn()},
+		})
+	}
+
+	return &awswafv2.CfnWebACL_RuleProperty{
+		Name:     jsii.String("BlockedCidrs"),
+		Priority: jsii.Number(0),
+		Statement: &awswafv2.CfnWebACL_StatementProperty{
+			OrStatement: &awswafv2.CfnWebACL_OrStatementProperty{Statements: &statements},
+		},
+		Action: &awswafv2.CfnWebACL_RuleActionProperty{
+			Block: &awswafv2.CfnWebACL_BlockActionProperty{},
+		},
+		VisibilityConfig: &awswafv2.CfnWebACL_VisibilityConfigProperty{
+			SampledRequestsEnabled:   jsii.Bool(true),
+			CloudWatchMetricsEnabled: jsii.Bool(true),
+			MetricName:               jsii.String("BlockedCidrsMetric"),
+		},
+	}
+}
@@ -0,0 +1,82 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+const (
+	// EnvironmentSuffixContextKey is the CDK context key for the suffix
+	// (cdk deploy -c environmentSuffix=pr123).
+	EnvironmentSuffixContextKey = "environmentSuffix"
+	// EnvironmentSuffixEnvVar is the environment variable read when neither
+	// props nor context set the suffix.
+	EnvironmentSuffixEnvVar = "ENVIRONMENT_SUFFIX"
+	// DefaultEnvironmentSuffix is used when no source sets the suffix.
+	DefaultEnvironmentSuffix = "dev"
+	// MaxEnvironmentSuffixLength keeps the longest default names
+	// (prod-<suffix>-vpc-flow-logs-<account> buckets, prod-<suffix>-alb)
+	// within the S3 and ELB limits without truncation.
+	MaxEnvironmentSuffixLength = 20
+)
+
+var environmentSuffixPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// ResolveEnvironmentSuffix returns the environment suffix from, in order of
+// precedence, the explicit value (usually TapStackProps.EnvironmentSuffix),
+// the environmentSuffix CDK context, the ENVIRONMENT_SUFFIX environment
+// variable and finally DefaultEnvironmentSuffix. The result is validated with
+// ValidateEnvironmentSuffix.
+func ResolveEnvironmentSuffix(scope constructs.Construct, explicit *string) (string, error) {
+	suffix, source, err := lookupEnvironmentSuffix(scope, explicit)
+	if err != nil {
+		return "", err
+	}
+	if err := ValidateEnvironmentSuffix(suffix); err != nil {
+		return "", fmt.Errorf("environment suffix from %s: %w", source, err)
+	}
+	return suffix, nil
+}
+
+// ValidateEnvironmentSuffix checks the suffix is safe to embed in bucket,
+// IAM and load balancer names.
+func ValidateEnvironmentSuffix(suffix string) error {
+	if len(suffix) > MaxEnvironmentSuffixLength {
+		return fmt.Errorf("%q is %d characters, the maximum is %d", suffix, len(suffix), MaxEnvironmentSuffixLength)
+	}
+	if !environmentSuffixPattern.MatchString(suffix) {
+		return fmt.Errorf("%q must be lowercase letters, digits and hyphens, and start and end with a letter or digit", suffix)
+	}
+	return nil
+}
+
+// lookupEnvironmentSuffix returns the first suffix set and a description of where it came from
+func lookupEnvironmentSuffix(scope constructs.Construct, explicit *string) (string, string, error) {
+	if explicit != nil {
+		return *explicit, "props", nil
+	}
+
+	if scope != nil {
+		switch value := scope.Node().TryGetContext(jsii.String(EnvironmentSuffixContextKey)).(type) {
+		case nil:
+		case string:
+			return value, "context", nil
+		case *string:
+			if value != nil {
+				return *value, "context", nil
+			}
+		default:
+			return "", "", fmt.Errorf("context %q must be a string, got %T", EnvironmentSuffixContextKey, value)
+		}
+	}
+
+	if value := os.Getenv(EnvironmentSuffixEnvVar); value != "" {
+		return value, EnvironmentSuffixEnvVar, nil
+	}
+
+	return DefaultEnvironmentSuffix, "default", nil
+}
@@ -0,0 +1,110 @@
+package lib
+
+import (
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsec2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslogs"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awss3"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// flowLogsPrefix is the flow logs bucket prefix the Parquet files are written under.
+const flowLogsPrefix = "vpc-flow-logs"
+
+// FlowLogField is a field of the custom flow log format and the type of its
+// column in the Glue table.
+type FlowLogField struct {
+	// Name is the flow log field, e.g. "pkt-srcaddr". Parquet delivery names
+	// the column with underscores instead of hyphens.
+	Name string
+	// Type is the Hive column type.
+	Type string
+}
+
+// FlowLogFields is the custom flow log format: the default version 2 fields
+// followed by the fields that show the original source and destination behind
+// a NAT gateway or load balancer, the TCP flags and the direction of the flow.
+var FlowLogFields = []FlowLogField{
+	{Name: "version", Type: "int"},
+	{Name: "account-id", Type: "string"},
+	{Name: "interface-id", Type: "string"},
+	{Name: "srcaddr", Type: "string"},
+	{Name: "dstaddr", Type: "string"},
+	{Name: "srcport", Type: "int"},
+	{Name: "dstport", Type: "int"},
+	{Name: "protocol", Type: "bigint"},
+	{Name: "packets", Type: "bigint"},
+	{Name: "bytes", Type: "bigint"},
+	{Name: "start", Type: "bigint"},
+	{Name: "end", Type: "bigint"},
+	{Name: "action", Type: "string"},
+	{Name: "log-status", Type: "string"},
+	{Name: "vpc-id", Type: "string"},
+	{Name: "subnet-id", Type: "string"},
+	{Name: "instance-id", Type: "string"},
+	{Name: "az-id", Type: "string"},
+	{Name: "type", Type: "string"},
+	{Name: "pkt-srcaddr", Type: "string"},
+	{Name: "pkt-dstaddr", Type: "string"},
+	{Name: "pkt-src-aws-service", Type: "string"},
+	{Name: "pkt-dst-aws-service", Type: "string"},
+	{Name: "tcp-flags", Type: "int"},
+	{Name: "flow-direction", Type: "string"},
+	{Name: "traffic-path", Type: "int"},
+}
+
+// flowLogFormat returns FlowLogFields as a flow log format
+func flowLogFormat() *[]awsec2.LogFormat {
+	format := make([]awsec2.LogFormat, 0, len(FlowLogFields))
+	for _, field := range FlowLogFields {
+		format = append(format, awsec2.LogFormat_Field(jsii.String(field.Name)))
+	}
+	return &format
+}
+
+// createFlowLogs enables VPC Flow Logs in the custom format to a centralized
+// S3 bucket as hourly, Hive-partitioned Parquet files and, when enabled, also
+// delivers them to CloudWatch Logs
+func (o *ObservabilityConstruct) createFlowLogs(props *ObservabilityConstructProps) {
+	if props.SkipFlowLogs {
+		return
+	}
+
+	o.FlowLogsBucket = awss3.NewBucket(o.Construct, jsii.String("VPCFlowLogsBucket"), &awss3.BucketProps{
+		BucketName:        bucketName(o.Construct, props.Namer, "vpc-flow-logs"),
+		Versioned:         jsii.Bool(true),
+		RemovalPolicy:     props.RemovalPolicy,
+		AutoDeleteObjects: autoDeleteObjects(props.RemovalPolicy),
+		BlockPublicAccess: awss3.BlockPublicAccess_BLOCK_ALL(),
+		EncryptionKey:     props.KmsKey,
+		Encryption:        awss3.BucketEncryption_KMS,
+	})
+
+	awsec2.NewFlowLog(o.Construct, jsii.String("VPCFlowLog"), &awsec2.FlowLogProps{
+		ResourceType: awsec2.FlowLogResourceType_FromVpc(props.Vpc),
+		Destination: awsec2.FlowLogDestination_ToS3(o.FlowLogsBucket, jsii.String(flowLogsPrefix+"/"), &awsec2.S3DestinationOptions{
+			FileFormat:               awsec2.FlowLogFileFormat_PARQUET,
+			HiveCompatiblePartitions: jsii.Bool(true),
+			PerHourPartition:         jsii.Bool(true),
+		}),
+		TrafficType: awsec2.FlowLogTrafficType_ALL,
+		LogFormat:   flowLogFormat(),
+	})
+
+	if props.FlowLogsCloudWatch {
+		o.FlowLogGroup = awslogs.NewLogGroup(o.Construct, jsii.String("VPCFlowLogGroup"), &awslogs.LogGroupProps{
+			LogGroupName:  jsii.String("/aws/vpc-flow-logs/" + props.Namer.Name(ResourceGeneric, "vpc")),
+			EncryptionKey: props.KmsKey,
+			Retention:     props.LogRetention,
+			RemovalPolicy: props.RemovalPolicy,
+		})
+		awsec2.NewFlowLog(o.Construct, jsii.String("VPCFlowLogCloudWatch"), &awsec2.FlowLogProps{
+			ResourceType: awsec2.FlowLogResourceType_FromVpc(props.Vpc),
+			Destination:  awsec2.FlowLogDestination_ToCloudWatchLogs(o.FlowLogGroup, nil),
+			TrafficType:  awsec2.FlowLogTrafficType_ALL,
+			LogFormat:    flowLogFormat(),
+		})
+	}
+
+	awscdk.Tags_Of(o.FlowLogsBucket).Add(jsii.String("Name"), resourceName(props.Namer, ResourceGeneric, "vpc-flow-logs"), nil)
+}
@@ -0,0 +1,215 @@
+package lib
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// KeyService is an AWS service that needs a key policy statement of its own
+// because it does not use the key through another service on behalf of an
+// IAM role of the account.
+type KeyService string
+
+const (
+	// KeyServiceLogs encrypts CloudWatch Logs log groups.
+	KeyServiceLogs KeyService = "logs"
+	// KeyServiceLogDelivery writes flow logs, Resolver query logs, Network
+	// Firewall logs and CloudFront access logs to KMS-encrypted buckets.
+	KeyServiceLogDelivery KeyService = "delivery.logs"
+	// KeyServiceCloudTrail encrypts the trail's log files.
+	KeyServiceCloudTrail KeyService = "cloudtrail"
+	// KeyServiceSessionManager encrypts Session Manager sessions. The SSM
+	// agent and the clients call KMS directly with their IAM credentials.
+	KeyServiceSessionManager KeyService = "ssm-sessions"
+)
+
+// keyAdminActions manage the key but cannot use it.
+var keyAdminActions = []string{
+	"kms:Create*",
+	"kms:Describe*",
+	"kms:Enable*",
+	"kms:List*",
+	"kms:Put*",
+	"kms:Update*",
+	"kms:Revoke*",
+	"kms:Disable*",
+	"kms:Get*",
+	"kms:Delete*",
+	"kms:TagResource",
+	"kms:UntagResource",
+	"kms:ScheduleKeyDeletion",
+	"kms:CancelKeyDeletion",
+}
+
+// keyUsageActions are the cryptographic operations IAM policies may grant.
+var keyUsageActions = []string{
+	"kms:Encrypt",
+	"kms:Decrypt",
+	"kms:ReEncrypt*",
+	"kms:GenerateDataKey*",
+	"kms:DescribeKey",
+}
+
+// trailName is the name of the audit trail, known before the trail exists so
+// the key policy can reference it.
+func trailName(namer Namer) *string {
+	return resourceName(namer, ResourceGeneric, "cloudtrail")
+}
+
+// newKeyPolicy returns a key policy that lets the account administer the key
+// and delegate its use to IAM through viaServices only, and lets each of
+// services use it only for the stack's own resources. Every service principal
+// statement is conditioned on the source account, the source ARN or the
+// encryption context.
+func newKeyPolicy(scope constructs.Construct, namer Namer, services []KeyService, viaServices []string) awsiam.PolicyDocument {
+	stack := awscdk.Stack_Of(scope)
+	root := awsiam.NewAccountRootPrincipal()
+	via := make([]string, 0, len(viaServices))
+	for _, service := range viaServices {
+		via = append(via, fmt.Sprintf("%s.%s.amazonaws.com", service, *stack.Region()))
+	}
+
+	statements := []awsiam.PolicyStatement{
+		awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+			Sid:        jsii.String("KeyAdministration"),
+			Effect:     awsiam.Effect_ALLOW,
+			Principals: &[]awsiam.IPrincipal{root},
+			Actions:    jsii.Strings(keyAdminActions...),
+			Resources:  jsii.Strings("*"),
+		}),
+		awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+			Sid:        jsii.String("KeyUsageThroughIam"),
+			Effect:     awsiam.Effect_ALLOW,
+			Principals: &[]awsiam.IPrincipal{root},
+			Actions:    jsii.Strings(keyUsageActions...),
+			Resources:  jsii.Strings("*"),
+			Conditions: &map[string]interface{}{
+				"StringEquals": map[string]interface{}{
+					"kms:CallerAccount": stack.Account(),
+					"kms:ViaService":    via,
+				},
+			},
+		}),
+	}
+
+	for _, service := range services {
+		switch service {
+		case KeyServiceLogs:
+			statements = append(statements, logsKeyStatement(stack))
+		case KeyServiceLogDelivery:
+			statements = append(statements, logDeliveryKeyStatement(stack))
+		case KeyServiceCloudTrail:
+			statements = append(statements, cloudTrailKeyStatements(stack, namer)...)
+		case KeyServiceSessionManager:
+			statements = append(statements, sessionManagerKeyStatement(stack))
+		}
+	}
+
+	return awsiam.NewPolicyDocument(&awsiam.PolicyDocumentProps{
+		Statements: &statements,
+	})
+}
+
+// logsKeyStatement lets CloudWatch Logs use the key for the account's log
+// groups in the stack's region, identified by the encryption context it sends.
+func logsKeyStatement(stack awscdk.Stack) awsiam.PolicyStatement {
+	return awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Sid:    jsii.String("CloudWatchLogsLogGroups"),
+		Effect: awsiam.Effect_ALLOW,
+		Principals: &[]awsiam.IPrincipal{
+			awsiam.NewServicePrincipal(jsii.String(fmt.Sprintf("logs.%s.amazonaws.com", *stack.Region())), nil),
+		},
+		Actions: jsii.Strings(
+			"kms:Encrypt*",
+			"kms:Decrypt*",
+			"kms:ReEncrypt*",
+			"kms:GenerateDataKey*",
+			"kms:Describe*",
+		),
+		Resources: jsii.Strings("*"),
+		Conditions: &map[string]interface{}{
+			"ArnLike": map[string]interface{}{
+				"kms:EncryptionContext:aws:logs:arn": fmt.Sprintf("arn:%s:logs:%s:%s:log-group:*",
+					*stack.Partition(), *stack.Region(), *stack.Account()),
+			},
+		},
+	})
+}
+
+// logDeliveryKeyStatement lets the log delivery service generate data keys
+// for the log files it writes to the account's buckets.
+func logDeliveryKeyStatement(stack awscdk.Stack) awsiam.PolicyStatement {
+	return awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Sid:    jsii.String("LogDelivery"),
+		Effect: awsiam.Effect_ALLOW,
+		Principals: &[]awsiam.IPrincipal{
+			awsiam.NewServicePrincipal(jsii.String("delivery.logs.amazonaws.com"), nil),
+		},
+		Actions:   jsii.Strings("kms:GenerateDataKey*"),
+		Resources: jsii.Strings("*"),
+		Conditions: &map[string]interface{}{
+			"StringEquals": map[string]interface{}{"aws:SourceAccount": stack.Account()},
+		},
+	})
+}
+
+// sessionManagerKeyStatement lets the account's instances and users encrypt
+// Session Manager sessions, identified by the encryption context the SSM
+// agent sends, without going through another service.
+func sessionManagerKeyStatement(stack awscdk.Stack) awsiam.PolicyStatement {
+	return awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Sid:        jsii.String("SessionManagerSessions"),
+		Effect:     awsiam.Effect_ALLOW,
+		Principals: &[]awsiam.IPrincipal{awsiam.NewAccountRootPrincipal()},
+		Actions:    jsii.Strings("kms:Decrypt", "kms:GenerateDataKey"),
+		Resources:  jsii.Strings("*"),
+		Conditions: &map[string]interface{}{
+			"StringEquals": map[string]interface{}{"kms:CallerAccount": stack.Account()},
+			"StringLike":   map[string]interface{}{"kms:EncryptionContext:aws:ssm:SessionId": "*"},
+		},
+	})
+}
+
+// cloudTrailKeyStatements are the statements CloudTrail requires to encrypt
+// the log files of the stack's trail: data keys bound to the trail's
+// encryption context, and DescribeKey to validate the key when the trail is
+// created or updated.
+func cloudTrailKeyStatements(stack awscdk.Stack, namer Namer) []awsiam.PolicyStatement {
+	principal := awsiam.NewServicePrincipal(jsii.String("cloudtrail.amazonaws.com"), nil)
+	trailArn := stack.FormatArn(&awscdk.ArnComponents{
+		Service:      jsii.String("cloudtrail"),
+		Resource:     jsii.String("trail"),
+		ResourceName: trailName(namer),
+	})
+
+	return []awsiam.PolicyStatement{
+		awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+			Sid:        jsii.String("CloudTrailEncryptLogs"),
+			Effect:     awsiam.Effect_ALLOW,
+			Principals: &[]awsiam.IPrincipal{principal},
+			Actions:    jsii.Strings("kms:GenerateDataKey*"),
+			Resources:  jsii.Strings("*"),
+			Conditions: &map[string]interface{}{
+				"StringEquals": map[string]interface{}{"aws:SourceArn": trailArn},
+				"StringLike": map[string]interface{}{
+					"kms:EncryptionContext:aws:cloudtrail:arn": fmt.Sprintf("arn:%s:cloudtrail:*:%s:trail/*",
+						*stack.Partition(), *stack.Account()),
+				},
+			},
+		}),
+		awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+			Sid:        jsii.String("CloudTrailDescribeKey"),
+			Effect:     awsiam.Effect_ALLOW,
+			Principals: &[]awsiam.IPrincipal{principal},
+			Actions:    jsii.Strings("kms:DescribeKey"),
+			Resources:  jsii.Strings("*"),
+			Conditions: &map[string]interface{}{
+				"StringEquals": map[string]interface{}{"aws:SourceArn": trailArn},
+			},
+		}),
+	}
+}
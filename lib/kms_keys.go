@@ -0,0 +1,145 @@
+package lib
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awskms"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// KeyDomain is a class of data that gets a KMS key of its own when
+// TapStackProps.KmsKeyPerDomain is set.
+type KeyDomain string
+
+const (
+	// KeyDomainAppData encrypts the application bucket, the Lambda's data and
+	// the Aurora cluster.
+	KeyDomainAppData KeyDomain = "app-data"
+	// KeyDomainLogs encrypts the logging, flow log and Athena results buckets,
+	// the log groups, the trail and Session Manager sessions.
+	KeyDomainLogs KeyDomain = "logs"
+	// KeyDomainSecrets encrypts the Secrets Manager secret.
+	KeyDomainSecrets KeyDomain = "secrets"
+	// KeyDomainBackups encrypts backup vaults and snapshot copies, so
+	// restoring a backup never needs a grant on the live data keys. The stack
+	// backs nothing up itself, so with KmsKeyPerDomain the key is only created
+	// by the first call to TapStack.BackupsKmsKey.
+	KeyDomainBackups KeyDomain = "backups"
+)
+
+// KeyDomains lists every data domain in the order their keys are created.
+var KeyDomains = []KeyDomain{KeyDomainAppData, KeyDomainLogs, KeyDomainSecrets, KeyDomainBackups}
+
+// keyDomainServices are the services that need statements of their own in a
+// domain's key policy; the other domains are only used through IAM.
+var keyDomainServices = map[KeyDomain][]KeyService{
+	KeyDomainLogs: {KeyServiceLogs, KeyServiceLogDelivery, KeyServiceCloudTrail, KeyServiceSessionManager},
+}
+
+// keyDomainViaServices are the services through which IAM principals of the
+// account may use a domain's key, the kms:ViaService condition of its policy.
+var keyDomainViaServices = map[KeyDomain][]string{
+	KeyDomainAppData: {"s3", "rds"},
+	KeyDomainLogs:    {"logs", "s3"},
+	KeyDomainSecrets: {"secretsmanager"},
+	KeyDomainBackups: {"backup"},
+}
+
+// id returns the domain in CamelCase for construct and output IDs, e.g. "AppData".
+func (d KeyDomain) id() string {
+	var id strings.Builder
+	for _, part := range strings.Split(string(d), "-") {
+		id.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return id.String()
+}
+
+// KmsKeys maps every data domain to the key that encrypts it. With a single
+// key, imported or created, every domain maps to that key.
+type KmsKeys map[KeyDomain]awskms.IKey
+
+// PerDomain reports whether the domains have keys of their own.
+func (k KmsKeys) PerDomain() bool {
+	return k[KeyDomainAppData] != k[KeyDomainLogs]
+}
+
+// sharedKmsKeys maps every domain to key
+func sharedKmsKeys(key awskms.IKey) KmsKeys {
+	keys := make(KmsKeys, len(KeyDomains))
+	for _, domain := range KeyDomains {
+		keys[domain] = key
+	}
+	return keys
+}
+
+// keyFactory creates the stack's customer-managed keys with a least-privilege
+// key policy, yearly rotation, an alias and a Name tag.
+type keyFactory struct {
+	scope         constructs.Construct
+	namer         Namer
+	removalPolicy awscdk.RemovalPolicy
+}
+
+// shared creates the single key used by every domain
+func (f keyFactory) shared() KmsKeys {
+	var services []KeyService
+	var viaServices []string
+	for _, domain := range KeyDomains {
+		services = append(services, keyDomainServices[domain]...)
+		for _, service := range keyDomainViaServices[domain] {
+			if !slices.Contains(viaServices, service) {
+				viaServices = append(viaServices, service)
+			}
+		}
+	}
+	key := f.newKey("ProdKMSKey", fmt.Sprintf("Customer-managed KMS key for %s environment", f.namer.Prefix()), "key", services, viaServices)
+	awscdk.Tags_Of(key).Add(jsii.String("Name"), resourceName(f.namer, ResourceGeneric, "kms-key"), nil)
+	return sharedKmsKeys(key)
+}
+
+// perDomain creates a key for each of KeyDomains except KeyDomainBackups,
+// which is created on demand by domainKey
+func (f keyFactory) perDomain() KmsKeys {
+	keys := make(KmsKeys, len(KeyDomains))
+	for _, domain := range KeyDomains {
+		if domain != KeyDomainBackups {
+			keys[domain] = f.domainKey(domain)
+		}
+	}
+	return keys
+}
+
+// domainKey creates the key of domain
+func (f keyFactory) domainKey(domain KeyDomain) awskms.Key {
+	key := f.newKey("ProdKMSKey"+domain.id(),
+		fmt.Sprintf("Customer-managed KMS key for %s %s", f.namer.Prefix(), domain),
+		string(domain)+"-key", keyDomainServices[domain], keyDomainViaServices[domain])
+	awscdk.Tags_Of(key).Add(jsii.String("Name"), resourceName(f.namer, ResourceGeneric, string(domain)+"-kms-key"), nil)
+	return key
+}
+
+// newKey creates a key that services, and IAM principals through
+// viaServices, may use and its "alias/<name>" alias, where name is the
+// namer's name for component
+func (f keyFactory) newKey(id, description, component string, services []KeyService, viaServices []string) awskms.Key {
+	key := awskms.NewKey(f.scope, jsii.String(id), &awskms.KeyProps{
+		Description: jsii.String(description),
+		KeySpec:     awskms.KeySpec_SYMMETRIC_DEFAULT,
+		KeyUsage:    awskms.KeyUsage_ENCRYPT_DECRYPT,
+		// Rotates the key material every year
+		EnableKeyRotation: jsii.Bool(true),
+		Policy:            newKeyPolicy(f.scope, f.namer, services, viaServices),
+		RemovalPolicy:     f.removalPolicy,
+	})
+
+	awskms.NewAlias(f.scope, jsii.String(id+"Alias"), &awskms.AliasProps{
+		AliasName: jsii.String("alias/" + f.namer.Name(ResourceGeneric, component)),
+		TargetKey: key,
+	})
+
+	return key
+}
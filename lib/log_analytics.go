@@ -0,0 +1,417 @@
+package lib
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsathena"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsglue"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awskms"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awss3"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// athenaResultsExpirationDays is how long Athena query results are kept.
+const athenaResultsExpirationDays = 30
+
+// Logging bucket prefixes CloudTrail and CloudFront write their logs under.
+const (
+	cloudTrailLogsPrefix = "cloudtrail-logs"
+	cloudFrontLogsPrefix = "cloudfront-logs"
+)
+
+// Names of the log tables in the Glue database.
+const (
+	FlowLogsTableName   = "vpc_flow_logs"
+	CloudTrailTableName = "cloudtrail_logs"
+	CloudFrontTableName = "cloudfront_logs"
+)
+
+// CloudTrailRegions are the values of the region partition of the CloudTrail
+// table. The trail is multi-region, so events of every enabled region are
+// delivered; add opt-in regions the account uses.
+var CloudTrailRegions = []string{
+	"us-east-1", "us-east-2", "us-west-1", "us-west-2",
+	"ca-central-1", "sa-east-1",
+	"eu-central-1", "eu-west-1", "eu-west-2", "eu-west-3", "eu-north-1",
+	"ap-south-1", "ap-northeast-1", "ap-northeast-2", "ap-northeast-3",
+	"ap-southeast-1", "ap-southeast-2",
+}
+
+// NamedQueryDefinition is an Athena query saved in the log workgroup.
+type NamedQueryDefinition struct {
+	// Name identifies the query in the Athena console.
+	Name        string
+	Description string
+	// Table is the log table the query reads; the query is skipped when the
+	// table is not created.
+	Table string
+	// Query is run against the log database.
+	Query string
+}
+
+// DefaultNamedQueries are saved in the log workgroup. Each query limits the
+// partitions it reads, so it scans at most a few days of logs.
+var DefaultNamedQueries = []NamedQueryDefinition{
+	{
+		Name:        "top-blocked-ips",
+		Description: "Source addresses with the most rejected inbound flows today",
+		Table:       FlowLogsTableName,
+		Query: `SELECT srcaddr, count(*) AS rejected_flows, count(DISTINCT dstport) AS ports
+FROM vpc_flow_logs
+WHERE action = 'REJECT' AND flow_direction = 'ingress'
+  AND year = date_format(current_date, '%Y') AND month = date_format(current_date, '%m') AND day = date_format(current_date, '%d')
+GROUP BY srcaddr
+ORDER BY rejected_flows DESC
+LIMIT 25`,
+	},
+	{
+		Name:        "root-activity",
+		Description: "API calls made with the root user in the last 7 days",
+		Table:       CloudTrailTableName,
+		Query: `SELECT eventtime, eventsource, eventname, awsregion, sourceipaddress, useragent, errorcode
+FROM cloudtrail_logs
+WHERE useridentity.type = 'Root'
+  AND timestamp >= date_format(current_date - interval '7' day, '%Y/%m/%d')
+ORDER BY eventtime DESC`,
+	},
+	{
+		Name:        "http-errors-by-path",
+		Description: "CloudFront 4xx and 5xx responses by path in the last day",
+		Table:       CloudFrontTableName,
+		Query: `SELECT uri, status, count(*) AS requests
+FROM cloudfront_logs
+WHERE status >= 400 AND "date" >= current_date - interval '1' day
+GROUP BY uri, status
+ORDER BY requests DESC
+LIMIT 50`,
+	},
+}
+
+// LogAnalyticsConstructProps defines the inputs of the LogAnalyticsConstruct.
+type LogAnalyticsConstructProps struct {
+	// Namer builds resource names.
+	Namer Namer
+	// KmsKey encrypts the query results.
+	KmsKey        awskms.IKey
+	RemovalPolicy awscdk.RemovalPolicy
+	// LogBucket holds the CloudTrail and CloudFront logs.
+	LogBucket awss3.IBucket
+	// FlowLogsBucket holds the Parquet flow logs; no flow log table is created when nil.
+	FlowLogsBucket awss3.IBucket
+}
+
+// LogAnalyticsConstruct registers the stack's logs as Glue tables and
+// saves DefaultNamedQueries in an Athena workgroup.
+type LogAnalyticsConstruct struct {
+	constructs.Construct
+	// Database holds the log tables, which are queried in WorkGroup.
+	Database      awsglue.CfnDatabase
+	WorkGroup     awsathena.CfnWorkGroup
+	ResultsBucket awss3.Bucket
+	// FlowLogsTable is nil without a FlowLogsBucket.
+	FlowLogsTable   awsglue.CfnTable
+	CloudTrailTable awsglue.CfnTable
+	CloudFrontTable awsglue.CfnTable
+	NamedQueries    []awsathena.CfnNamedQuery
+}
+
+// NewLogAnalyticsConstruct creates the log database, its tables, the Athena
+// workgroup and the named queries.
+func NewLogAnalyticsConstruct(scope constructs.Construct, id *string, props *LogAnalyticsConstructProps) *LogAnalyticsConstruct {
+	analytics := &LogAnalyticsConstruct{
+		Construct: constructs.NewConstruct(scope, id),
+	}
+
+	analytics.createWorkGroup(props)
+	analytics.createFlowLogsTable(props)
+	analytics.createCloudTrailTable(props)
+	analytics.createCloudFrontTable(props)
+	analytics.createNamedQueries(props)
+
+	return analytics
+}
+
+// glueName turns a resource name into a Glue/Athena identifier, which may
+// only hold lower-case letters, digits and underscores
+func glueName(name string) *string {
+	return jsii.String(strings.ReplaceAll(strings.ToLower(name), "-", "_"))
+}
+
+// glueColumns builds table columns from name/type pairs
+func glueColumns(columns [][2]string) *[]interface{} {
+	result := make([]interface{}, 0, len(columns))
+	for _, column := range columns {
+		result = append(result, &awsglue.CfnTable_ColumnProperty{
+			Name: jsii.String(column[0]),
+			Type: jsii.String(column[1]),
+		})
+	}
+	return &result
+}
+
+// createWorkGroup creates the Glue database the log tables are registered in
+// and an Athena workgroup whose results are encrypted with the stack's key
+func (a *LogAnalyticsConstruct) createWorkGroup(props *LogAnalyticsConstructProps) {
+	a.Database = awsglue.NewCfnDatabase(a.Construct, jsii.String("LogDatabase"), &awsglue.CfnDatabaseProps{
+		CatalogId: awscdk.Stack_Of(a.Construct).Account(),
+		DatabaseInput: &awsglue.CfnDatabase_DatabaseInputProperty{
+			Name:        glueName(props.Namer.Name(ResourceGeneric, "logs")),
+			Description: jsii.String("Log tables of " + props.Namer.Prefix()),
+		},
+	})
+
+	a.ResultsBucket = awss3.NewBucket(a.Construct, jsii.String("AthenaResultsBucket"), &awss3.BucketProps{
+		BucketName:        bucketName(a.Construct, props.Namer, "athena-results"),
+		RemovalPolicy:     props.RemovalPolicy,
+		AutoDeleteObjects: autoDeleteObjects(props.RemovalPolicy),
+		BlockPublicAccess: awss3.BlockPublicAccess_BLOCK_ALL(),
+		EncryptionKey:     props.KmsKey,
+		Encryption:        awss3.BucketEncryption_KMS,
+		LifecycleRules: &[]*awss3.LifecycleRule{
+			{Expiration: awscdk.Duration_Days(jsii.Number(athenaResultsExpirationDays))},
+		},
+	})
+
+	// Enforcing the workgroup configuration stops clients from writing
+	// unencrypted results elsewhere
+	a.WorkGroup = awsathena.NewCfnWorkGroup(a.Construct, jsii.String("AthenaWorkGroup"), &awsathena.CfnWorkGroupProps{
+		Name:                  resourceName(props.Namer, ResourceGeneric, "logs"),
+		Description:           jsii.String("Log queries of " + props.Namer.Prefix()),
+		RecursiveDeleteOption: jsii.Bool(props.RemovalPolicy == awscdk.RemovalPolicy_DESTROY),
+		WorkGroupConfiguration: &awsathena.CfnWorkGroup_WorkGroupConfigurationProperty{
+			EnforceWorkGroupConfiguration:   jsii.Bool(true),
+			PublishCloudWatchMetricsEnabled: jsii.Bool(true),
+			ResultConfiguration: &awsathena.CfnWorkGroup_ResultConfigurationProperty{
+				OutputLocation: jsii.String("s3://" + *a.ResultsBucket.BucketName() + "/"),
+				EncryptionConfiguration: &awsathena.CfnWorkGroup_EncryptionConfigurationProperty{
+					EncryptionOption: jsii.String("SSE_KMS"),
+					KmsKey:           props.KmsKey.KeyArn(),
+				},
+			},
+		},
+	})
+
+	awscdk.Tags_Of(a.ResultsBucket).Add(jsii.String("Name"), resourceName(props.Namer, ResourceGeneric, "athena-results"), nil)
+	awscdk.Tags_Of(a.WorkGroup).Add(jsii.String("Name"), resourceName(props.Namer, ResourceGeneric, "logs"), nil)
+}
+
+// createFlowLogsTable registers the Parquet flow logs of this account and
+// region. Partition projection derives the hourly partitions from the
+// delivery path, so no crawler or MSCK REPAIR is needed.
+func (a *LogAnalyticsConstruct) createFlowLogsTable(props *LogAnalyticsConstructProps) {
+	if props.FlowLogsBucket == nil {
+		return
+	}
+
+	stack := awscdk.Stack_Of(a.Construct)
+	location := "s3://" + *props.FlowLogsBucket.BucketName() + "/" + flowLogsPrefix + "/AWSLogs/aws-account-id=" +
+		*stack.Account() + "/aws-service=vpcflowlogs/aws-region=" + *stack.Region() + "/"
+
+	var columns [][2]string
+	for _, field := range FlowLogFields {
+		columns = append(columns, [2]string{strings.ReplaceAll(field.Name, "-", "_"), field.Type})
+	}
+
+	a.FlowLogsTable = awsglue.NewCfnTable(a.Construct, jsii.String("FlowLogsTable"), &awsglue.CfnTableProps{
+		CatalogId:    stack.Account(),
+		DatabaseName: a.Database.Ref(),
+		TableInput: &awsglue.CfnTable_TableInputProperty{
+			Name:          jsii.String(FlowLogsTableName),
+			Description:   jsii.String("VPC flow logs of " + props.Namer.Prefix()),
+			TableType:     jsii.String("EXTERNAL_TABLE"),
+			PartitionKeys: glueColumns([][2]string{{"year", "string"}, {"month", "string"}, {"day", "string"}, {"hour", "string"}}),
+			Parameters: &map[string]interface{}{
+				"EXTERNAL":                  "TRUE",
+				"classification":            "parquet",
+				"projection.enabled":        "true",
+				"projection.year.type":      "integer",
+				"projection.year.range":     "2020,2099",
+				"projection.month.type":     "integer",
+				"projection.month.range":    "1,12",
+				"projection.month.digits":   "2",
+				"projection.day.type":       "integer",
+				"projection.day.range":      "1,31",
+				"projection.day.digits":     "2",
+				"projection.hour.type":      "integer",
+				"projection.hour.range":     "0,23",
+				"projection.hour.digits":    "2",
+				"storage.location.template": location + "year=${year}/month=${month}/day=${day}/hour=${hour}/",
+			},
+			StorageDescriptor: &awsglue.CfnTable_StorageDescriptorProperty{
+				Columns:      glueColumns(columns),
+				Location:     jsii.String(location),
+				InputFormat:  jsii.String("org.apache.hadoop.hive.ql.io.parquet.MapredParquetInputFormat"),
+				OutputFormat: jsii.String("org.apache.hadoop.hive.ql.io.parquet.MapredParquetOutputFormat"),
+				SerdeInfo: &awsglue.CfnTable_SerdeInfoProperty{
+					SerializationLibrary: jsii.String("org.apache.hadoop.hive.ql.io.parquet.serde.ParquetHiveSerDe"),
+				},
+			},
+		},
+	})
+}
+
+// createCloudTrailTable registers the trail's log files of this account,
+// projecting the region and delivery-date partitions from the key layout
+// AWSLogs/<account>/CloudTrail/<region>/<yyyy>/<MM>/<dd>/
+func (a *LogAnalyticsConstruct) createCloudTrailTable(props *LogAnalyticsConstructProps) {
+	stack := awscdk.Stack_Of(a.Construct)
+	location := "s3://" + *props.LogBucket.BucketName() + "/" + cloudTrailLogsPrefix + "/AWSLogs/" + *stack.Account() + "/CloudTrail/"
+
+	a.CloudTrailTable = awsglue.NewCfnTable(a.Construct, jsii.String("CloudTrailTable"), &awsglue.CfnTableProps{
+		CatalogId:    stack.Account(),
+		DatabaseName: a.Database.Ref(),
+		TableInput: &awsglue.CfnTable_TableInputProperty{
+			Name:          jsii.String(CloudTrailTableName),
+			Description:   jsii.String("CloudTrail events of " + props.Namer.Prefix()),
+			TableType:     jsii.String("EXTERNAL_TABLE"),
+			PartitionKeys: glueColumns([][2]string{{"region", "string"}, {"timestamp", "string"}}),
+			Parameters: &map[string]interface{}{
+				"EXTERNAL":                           "TRUE",
+				"classification":                     "cloudtrail",
+				"projection.enabled":                 "true",
+				"projection.region.type":             "enum",
+				"projection.region.values":           strings.Join(CloudTrailRegions, ","),
+				"projection.timestamp.type":          "date",
+				"projection.timestamp.format":        "yyyy/MM/dd",
+				"projection.timestamp.range":         "2020/01/01,NOW",
+				"projection.timestamp.interval":      "1",
+				"projection.timestamp.interval.unit": "DAYS",
+				"storage.location.template":          location + "${region}/${timestamp}/",
+			},
+			StorageDescriptor: &awsglue.CfnTable_StorageDescriptorProperty{
+				Columns: glueColumns([][2]string{
+					{"eventversion", "string"},
+					{"useridentity", "struct<type:string,principalid:string,arn:string,accountid:string,invokedby:string,accesskeyid:string,username:string," +
+						"sessioncontext:struct<attributes:struct<mfaauthenticated:string,creationdate:string>," +
+						"sessionissuer:struct<type:string,principalid:string,arn:string,accountid:string,username:string>,sourceidentity:string>>"},
+					{"eventtime", "string"},
+					{"eventsource", "string"},
+					{"eventname", "string"},
+					{"awsregion", "string"},
+					{"sourceipaddress", "string"},
+					{"useragent", "string"},
+					{"errorcode", "string"},
+					{"errormessage", "string"},
+					{"requestparameters", "string"},
+					{"responseelements", "string"},
+					{"additionaleventdata", "string"},
+					{"requestid", "string"},
+					{"eventid", "string"},
+					{"resources", "array<struct<arn:string,accountid:string,type:string>>"},
+					{"eventtype", "string"},
+					{"apiversion", "string"},
+					{"readonly", "string"},
+					{"recipientaccountid", "string"},
+					{"serviceeventdetails", "string"},
+					{"sharedeventid", "string"},
+					{"vpcendpointid", "string"},
+					{"tlsdetails", "struct<tlsversion:string,ciphersuite:string,clienthostname:string>"},
+				}),
+				Location:     jsii.String(location),
+				InputFormat:  jsii.String("com.amazon.emr.cloudtrail.CloudTrailInputFormat"),
+				OutputFormat: jsii.String("org.apache.hadoop.hive.ql.io.HiveIgnoreKeyTextOutputFormat"),
+				SerdeInfo: &awsglue.CfnTable_SerdeInfoProperty{
+					SerializationLibrary: jsii.String("org.apache.hive.hcatalog.data.JsonSerDe"),
+				},
+			},
+		},
+	})
+}
+
+// createCloudFrontTable registers the distribution's standard access logs.
+// CloudFront writes every log file directly under the prefix, with the date
+// only in the file name, so the table has no partitions to project; filter
+// on the "date" column and expect queries to scan the whole prefix.
+func (a *LogAnalyticsConstruct) createCloudFrontTable(props *LogAnalyticsConstructProps) {
+	stack := awscdk.Stack_Of(a.Construct)
+
+	a.CloudFrontTable = awsglue.NewCfnTable(a.Construct, jsii.String("CloudFrontTable"), &awsglue.CfnTableProps{
+		CatalogId:    stack.Account(),
+		DatabaseName: a.Database.Ref(),
+		TableInput: &awsglue.CfnTable_TableInputProperty{
+			Name:        jsii.String(CloudFrontTableName),
+			Description: jsii.String("CloudFront access logs of " + props.Namer.Prefix()),
+			TableType:   jsii.String("EXTERNAL_TABLE"),
+			Parameters: &map[string]interface{}{
+				"EXTERNAL":               "TRUE",
+				"classification":         "csv",
+				"skip.header.line.count": "2",
+			},
+			StorageDescriptor: &awsglue.CfnTable_StorageDescriptorProperty{
+				Columns: glueColumns([][2]string{
+					{"date", "date"},
+					{"time", "string"},
+					{"location", "string"},
+					{"bytes", "bigint"},
+					{"request_ip", "string"},
+					{"method", "string"},
+					{"host", "string"},
+					{"uri", "string"},
+					{"status", "int"},
+					{"referrer", "string"},
+					{"user_agent", "string"},
+					{"query_string", "string"},
+					{"cookie", "string"},
+					{"result_type", "string"},
+					{"request_id", "string"},
+					{"host_header", "string"},
+					{"request_protocol", "string"},
+					{"request_bytes", "bigint"},
+					{"time_taken", "float"},
+					{"xforwarded_for", "string"},
+					{"ssl_protocol", "string"},
+					{"ssl_cipher", "string"},
+					{"response_result_type", "string"},
+					{"http_version", "string"},
+					{"fle_status", "string"},
+					{"fle_encrypted_fields", "int"},
+					{"c_port", "int"},
+					{"time_to_first_byte", "float"},
+					{"x_edge_detailed_result_type", "string"},
+					{"sc_content_type", "string"},
+					{"sc_content_len", "bigint"},
+					{"sc_range_start", "bigint"},
+					{"sc_range_end", "bigint"},
+				}),
+				Location:     jsii.String("s3://" + *props.LogBucket.BucketName() + "/" + cloudFrontLogsPrefix + "/"),
+				InputFormat:  jsii.String("org.apache.hadoop.mapred.TextInputFormat"),
+				OutputFormat: jsii.String("org.apache.hadoop.hive.ql.io.HiveIgnoreKeyTextOutputFormat"),
+				SerdeInfo: &awsglue.CfnTable_SerdeInfoProperty{
+					SerializationLibrary: jsii.String("org.apache.hadoop.hive.serde2.lazy.LazySimpleSerDe"),
+					Parameters: &map[string]interface{}{
+						"field.delim":          "\t",
+						"serialization.format": "\t",
+					},
+				},
+			},
+		},
+	})
+}
+
+// createNamedQueries saves DefaultNamedQueries whose table exists
+func (a *LogAnalyticsConstruct) createNamedQueries(props *LogAnalyticsConstructProps) {
+	tables := map[string]awsglue.CfnTable{
+		FlowLogsTableName:   a.FlowLogsTable,
+		CloudTrailTableName: a.CloudTrailTable,
+		CloudFrontTableName: a.CloudFrontTable,
+	}
+	for i, definition := range DefaultNamedQueries {
+		table := tables[definition.Table]
+		if table == nil {
+			continue
+		}
+		query := awsathena.NewCfnNamedQuery(a.Construct, jsii.String(fmt.Sprintf("NamedQuery%d", i+1)), &awsathena.CfnNamedQueryProps{
+			Name:        resourceName(props.Namer, ResourceGeneric, definition.Name),
+			Description: jsii.String(definition.Description),
+			Database:    a.Database.Ref(),
+			WorkGroup:   a.WorkGroup.Ref(),
+			QueryString: jsii.String(definition.Query),
+		})
+		// The query reads the table by name, which is not a reference
+		query.AddDependency(table)
+		a.NamedQueries = append(a.NamedQueries, query)
+	}
+}
@@ -0,0 +1,88 @@
+package lib
+
+import (
+	"crypto/md5"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// nonAlphanumeric matches the characters CDK drops from logical IDs.
+var nonAlphanumeric = regexp.MustCompile(`[^A-Za-z0-9]`)
+
+// retainStackLevelLogicalIds gives every CloudFormation resource below the
+// scopes the logical ID it had when it was created directly in the stack,
+// before TapStack was split into constructs. A new logical ID replaces the
+// resource: the VPC, KMS key, buckets, database and secret would lose their
+// data, and resources with a fixed name would fail to deploy.
+func retainStackLevelLogicalIds(stack awscdk.Stack, scopes ...constructs.Construct) {
+	depth := len(strings.Split(*stack.Node().Path(), "/"))
+	// withoutScope drops the scope's own ID from the construct path, as if its
+	// children were the stack's
+	withoutScope := func(c constructs.IConstruct) []string {
+		path := strings.Split(*c.Node().Path(), "/")
+		return append(path[:depth:depth], path[depth+1:]...)
+	}
+
+	// Some construct IDs embed the unique ID of another construct, such as
+	// security group rules naming their peer
+	var children []constructs.IConstruct
+	uniqueIds := map[string]string{}
+	for _, scope := range scopes {
+		for _, child := range *scope.Node().FindAll(constructs.ConstructOrder_PREORDER) {
+			children = append(children, child)
+			uniqueIds[*awscdk.Names_UniqueId(child)] = stackLevelLogicalId(withoutScope(child))
+		}
+	}
+
+	for _, child := range children {
+		if !*awscdk.CfnResource_IsCfnResource(child) {
+			continue
+		}
+		path := withoutScope(child)[depth:]
+		for i := range path {
+			for uniqueId, stackLevel := range uniqueIds {
+				path[i] = strings.ReplaceAll(path[i], uniqueId, stackLevel)
+			}
+		}
+		child.(awscdk.CfnResource).OverrideLogicalId(jsii.String(stackLevelLogicalId(path)))
+	}
+}
+
+// stackLevelLogicalId allocates an ID from a construct path the way CDK does
+// for logical IDs (relative to the stack) and unique IDs (from the app):
+// readable path components followed by a hash of the full path
+func stackLevelLogicalId(path []string) string {
+	path = slices.DeleteFunc(slices.Clone(path), func(id string) bool { return id == "Default" })
+	if len(path) == 1 {
+		if id := nonAlphanumeric.ReplaceAllString(path[0], ""); len(id) <= 255 {
+			return id
+		}
+	}
+
+	// Components repeating the end of the previous one and "Resource" are not
+	// spelled out
+	var readable strings.Builder
+	previous := ""
+	for _, id := range path {
+		if previous != "" && strings.HasSuffix(previous, id) {
+			continue
+		}
+		previous = id
+		if id != "Resource" {
+			readable.WriteString(nonAlphanumeric.ReplaceAllString(id, ""))
+		}
+	}
+	human := readable.String()
+	if len(human) > 240 {
+		human = human[:240]
+	}
+
+	hash := md5.Sum([]byte(strings.Join(path, "/")))
+	return human + strings.ToUpper(fmt.Sprintf("%x", hash)[:8])
+}
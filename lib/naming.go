@@ -0,0 +1,191 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// ResourceKind identifies the naming constraints of an AWS resource type.
+type ResourceKind string
+
+const (
+	// ResourceGeneric has no length limit (tags, export names, topics, ...).
+	ResourceGeneric ResourceKind = "generic"
+	// ResourceS3Bucket names are lowercase and are suffixed with "-<account-id>"
+	// by the stack, so 13 of the 63 allowed characters are reserved.
+	ResourceS3Bucket ResourceKind = "s3-bucket"
+	// ResourceIamRole names are limited to 64 characters.
+	ResourceIamRole ResourceKind = "iam-role"
+	// ResourceLambdaFunction names are limited to 64 characters.
+	ResourceLambdaFunction ResourceKind = "lambda-function"
+	// ResourceLoadBalancer names are limited to 32 characters.
+	ResourceLoadBalancer ResourceKind = "load-balancer"
+	// ResourceTargetGroup names are limited to 32 characters.
+	ResourceTargetGroup ResourceKind = "target-group"
+	// ResourceDatabaseCluster identifiers are lowercase and limited to 63 characters.
+	ResourceDatabaseCluster ResourceKind = "database-cluster"
+)
+
+// accountSuffixLength is the "-<12 digit account id>" appended to bucket names.
+const accountSuffixLength = 13
+
+var resourceNameLimits = map[ResourceKind]int{
+	ResourceS3Bucket:        63 - accountSuffixLength,
+	ResourceIamRole:         64,
+	ResourceLambdaFunction:  64,
+	ResourceLoadBalancer:    32,
+	ResourceTargetGroup:     32,
+	ResourceDatabaseCluster: 63,
+}
+
+var lowercaseResourceKinds = map[ResourceKind]bool{
+	ResourceS3Bucket:        true,
+	ResourceDatabaseCluster: true,
+}
+
+// Namer builds the physical names of every resource created by the stacks.
+type Namer interface {
+	// Prefix returns the shared prefix used in tags, paths and descriptions
+	// (e.g. "prod-dev").
+	Prefix() string
+	// Name returns the name of a resource component (e.g. "lambda-role"),
+	// honouring the length limit and casing rules of the resource kind.
+	Name(kind ResourceKind, component string) string
+}
+
+// DefaultNamer joins organization, application, environment and region short
+// code with hyphens. With only Application "prod" and an Environment set it
+// reproduces the historical "prod-<suffix>-<component>" names.
+type DefaultNamer struct {
+	// Organization is an optional leading prefix (e.g. "acme").
+	Organization string
+	// Application is the application name. Defaults to "prod".
+	Application string
+	// Environment is the environment suffix (e.g. "dev").
+	Environment string
+	// Region, when set, adds its short code (e.g. eu-west-1 -> euw1).
+	Region string
+}
+
+// namePartPattern matches the letters, digits and inner hyphens that keep
+// every name, including lower-cased S3 bucket names, DNS-safe.
+var namePartPattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?$`)
+
+// Validate checks that Organization and Application only contain characters
+// allowed in every resource name. The Environment is validated by
+// ResolveEnvironmentSuffix.
+func (n *DefaultNamer) Validate() error {
+	var errs []error
+	for _, part := range []struct {
+		field string
+		value string
+	}{
+		{"Organization", n.Organization},
+		{"Application", n.Application},
+	} {
+		if part.value != "" && !namePartPattern.MatchString(part.value) {
+			errs = append(errs, fmt.Errorf("namer: %s %q must be letters, digits and hyphens, and start and end with a letter or digit", part.field, part.value))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// NewDefaultNamer returns the namer that preserves the historical naming.
+func NewDefaultNamer(environment string) *DefaultNamer {
+	return &DefaultNamer{
+		Application: "prod",
+		Environment: environment,
+	}
+}
+
+// Prefix implements Namer.
+func (n *DefaultNamer) Prefix() string {
+	application := n.Application
+	if application == "" {
+		application = "prod"
+	}
+
+	parts := []string{}
+	for _, part := range []string{n.Organization, application, n.Environment, RegionShortCode(n.Region)} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+// Name implements Namer.
+func (n *DefaultNamer) Name(kind ResourceKind, component string) string {
+	name := n.Prefix()
+	if component != "" {
+		name += "-" + component
+	}
+	if lowercaseResourceKinds[kind] {
+		name = strings.ToLower(name)
+	}
+	return TruncateName(name, resourceNameLimits[kind])
+}
+
+// resourceName returns the physical name of a component as a jsii string
+func resourceName(namer Namer, kind ResourceKind, component string) *string {
+	return jsii.String(namer.Name(kind, component))
+}
+
+// bucketName returns a globally unique bucket name suffixed with the account ID of scope's stack
+func bucketName(scope constructs.Construct, namer Namer, component string) *string {
+	return jsii.String(fmt.Sprintf("%s-%s", namer.Name(ResourceS3Bucket, component), *awscdk.Stack_Of(scope).Account()))
+}
+
+// TruncateName shortens a name to maxLength characters, replacing the tail
+// with a short hash of the full name so truncated names stay unique. A
+// maxLength of zero means no limit. When the limit leaves no room for a
+// hyphen and part of the name, the (possibly shortened) hash is returned.
+func TruncateName(name string, maxLength int) string {
+	if maxLength <= 0 || len(name) <= maxLength {
+		return name
+	}
+
+	hash := fnv.New32a()
+	hash.Write([]byte(name))
+	suffix := fmt.Sprintf("%08x", hash.Sum32())[:6]
+	if maxLength <= len(suffix)+1 {
+		return suffix[:min(maxLength, len(suffix))]
+	}
+
+	head := strings.TrimRight(name[:maxLength-len(suffix)-1], "-")
+	if head == "" {
+		return suffix
+	}
+	return head + "-" + suffix
+}
+
+var regionDirectionCodes = map[string]string{
+	"north":     "n",
+	"south":     "s",
+	"east":      "e",
+	"west":      "w",
+	"central":   "c",
+	"northeast": "ne",
+	"northwest": "nw",
+	"southeast": "se",
+	"southwest": "sw",
+}
+
+// RegionShortCode abbreviates a region name, e.g. us-east-1 -> use1,
+// ap-southeast-2 -> apse2. Unknown formats are returned without hyphens.
+func RegionShortCode(region string) string {
+	parts := strings.Split(region, "-")
+	for i, part := range parts {
+		if code, ok := regionDirectionCodes[part]; ok {
+			parts[i] = code
+		}
+	}
+	return strings.Join(parts, "")
+}
@@ -0,0 +1,205 @@
+package lib
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsec2"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// SubnetTier names one of the three subnet tiers of the VPC.
+type SubnetTier string
+
+const (
+	// SubnetTierPublic holds the ALB, bastion and NAT.
+	SubnetTierPublic SubnetTier = "public"
+	// SubnetTierPrivate is the app tier (EC2 instances and Lambda functions).
+	SubnetTierPrivate SubnetTier = "private"
+	// SubnetTierIsolated is the data tier (Aurora).
+	SubnetTierIsolated SubnetTier = "isolated"
+)
+
+// NaclPeer is the address range a network ACL rule matches.
+type NaclPeer string
+
+const (
+	// NaclPeerAnywhere matches 0.0.0.0/0 (and ::/0 in dual-stack mode).
+	NaclPeerAnywhere NaclPeer = "anywhere"
+	// NaclPeerVpc matches the VPC CIDR (and its IPv6 block in dual-stack mode).
+	NaclPeerVpc NaclPeer = "vpc"
+	// NaclPeerTransitGateway matches each Transit Gateway destination CIDR,
+	// numbered Number, Number+1 and so on. IPv4 only; without a Transit
+	// Gateway the rule is skipped.
+	NaclPeerTransitGateway NaclPeer = "transit-gateway"
+	// NaclPeerBastionAllowlist matches each BastionAllowedCidrs entry, numbered
+	// like NaclPeerTransitGateway. Outside BastionModeCidrAllowlist the rule is
+	// skipped.
+	NaclPeerBastionAllowlist NaclPeer = "bastion-allowlist"
+)
+
+// NaclProtocol is the IP protocol a network ACL rule matches.
+type NaclProtocol string
+
+const (
+	NaclProtocolAll NaclProtocol = "all"
+	NaclProtocolTcp NaclProtocol = "tcp"
+	NaclProtocolUdp NaclProtocol = "udp"
+)
+
+// Ephemeral ports used by clients for return traffic. NACLs are stateless, so
+// responses to outbound connections must be allowed back in explicitly. The
+// range matches NAT gateways; the app tier skips RdpPort within it so RDP is
+// never reachable from the internet.
+const (
+	EphemeralPortStart = 1024
+	EphemeralPortEnd   = 65535
+	RdpPort            = 3389
+)
+
+// NaclRule is one allow entry of a tier's network ACL. Anything not allowed is
+// denied by the ACL's implicit final rule.
+type NaclRule struct {
+	// Number orders the rule within its direction. Numbers are multiples of
+	// 10 so the IPv6 copy can take Number+1.
+	Number    float64
+	Direction awsec2.TrafficDirection
+	Peer      NaclPeer
+	Protocol  NaclProtocol
+	// FromPort and ToPort bound the TCP or UDP port range. Ignored for NaclProtocolAll.
+	FromPort float64
+	ToPort   float64
+	// Https limits the rule to stacks whose ALB has an HTTPS listener.
+	Https bool
+}
+
+// DefaultNetworkAclRules is the rule table applied to each tier. The app and
+// data tiers only accept traffic from inside the VPC and the Transit Gateway
+// destinations plus, for the app tier, return traffic on ephemeral ports. The
+// public tier only accepts SSH from the bastion allowlist, and HTTPS only when
+// the ALB listens on it.
+var DefaultNetworkAclRules = map[SubnetTier][]NaclRule{
+	SubnetTierPublic: {
+		{Number: 100, Direction: awsec2.TrafficDirection_INGRESS, Peer: NaclPeerAnywhere, Protocol: NaclProtocolTcp, FromPort: 443, ToPort: 443, Https: true},
+		{Number: 110, Direction: awsec2.TrafficDirection_INGRESS, Peer: NaclPeerAnywhere, Protocol: NaclProtocolTcp, FromPort: 80, ToPort: 80},
+		{Number: 130, Direction: awsec2.TrafficDirection_INGRESS, Peer: NaclPeerVpc, Protocol: NaclProtocolAll},
+		{Number: 140, Direction: awsec2.TrafficDirection_INGRESS, Peer: NaclPeerAnywhere, Protocol: NaclProtocolTcp, FromPort: EphemeralPortStart, ToPort: EphemeralPortEnd},
+		{Number: 150, Direction: awsec2.TrafficDirection_INGRESS, Peer: NaclPeerAnywhere, Protocol: NaclProtocolUdp, FromPort: EphemeralPortStart, ToPort: EphemeralPortEnd},
+		{Number: 100, Direction: awsec2.TrafficDirection_EGRESS, Peer: NaclPeerAnywhere, Protocol: NaclProtocolAll},
+		{Number: 300, Direction: awsec2.TrafficDirection_INGRESS, Peer: NaclPeerBastionAllowlist, Protocol: NaclProtocolTcp, FromPort: 22, ToPort: 22},
+	},
+	SubnetTierPrivate: {
+		{Number: 100, Direction: awsec2.TrafficDirection_INGRESS, Peer: NaclPeerVpc, Protocol: NaclProtocolAll},
+		{Number: 110, Direction: awsec2.TrafficDirection_INGRESS, Peer: NaclPeerAnywhere, Protocol: NaclProtocolTcp, FromPort: EphemeralPortStart, ToPort: RdpPort - 1},
+		{Number: 120, Direction: awsec2.TrafficDirection_INGRESS, Peer: NaclPeerAnywhere, Protocol: NaclProtocolTcp, FromPort: RdpPort + 1, ToPort: EphemeralPortEnd},
+		{Number: 130, Direction: awsec2.TrafficDirection_INGRESS, Peer: NaclPeerAnywhere, Protocol: NaclProtocolUdp, FromPort: EphemeralPortStart, ToPort: RdpPort - 1},
+		{Number: 140, Direction: awsec2.TrafficDirection_INGRESS, Peer: NaclPeerAnywhere, Protocol: NaclProtocolUdp, FromPort: RdpPort + 1, ToPort: EphemeralPortEnd},
+		{Number: 100, Direction: awsec2.TrafficDirection_EGRESS, Peer: NaclPeerAnywhere, Protocol: NaclProtocolAll},
+		{Number: 200, Direction: awsec2.TrafficDirection_INGRESS, Peer: NaclPeerTransitGateway, Protocol: NaclProtocolAll},
+	},
+	SubnetTierIsolated: {
+		{Number: 100, Direction: awsec2.TrafficDirection_INGRESS, Peer: NaclPeerVpc, Protocol: NaclProtocolAll},
+		{Number: 100, Direction: awsec2.TrafficDirection_EGRESS, Peer: NaclPeerVpc, Protocol: NaclProtocolAll},
+		{Number: 200, Direction: awsec2.TrafficDirection_INGRESS, Peer: NaclPeerTransitGateway, Protocol: NaclProtocolAll},
+		{Number: 200, Direction: awsec2.TrafficDirection_EGRESS, Peer: NaclPeerTransitGateway, Protocol: NaclProtocolAll},
+	},
+}
+
+// traffic converts the rule's protocol and ports to a CDK ACL traffic matcher
+func (r NaclRule) traffic() awsec2.AclTraffic {
+	switch r.Protocol {
+	case NaclProtocolTcp:
+		return awsec2.AclTraffic_TcpPortRange(jsii.Number(r.FromPort), jsii.Number(r.ToPort))
+	case NaclProtocolUdp:
+		return awsec2.AclTraffic_UdpPortRange(jsii.Number(r.FromPort), jsii.Number(r.ToPort))
+	case NaclProtocolAll:
+		return awsec2.AclTraffic_AllTraffic()
+	default:
+		panic(fmt.Sprintf("unsupported network ACL protocol %q", r.Protocol))
+	}
+}
+
+// createNetworkAcls replaces the default allow-all NACL of each tier with one
+// built from DefaultNetworkAclRules
+func (n *NetworkConstruct) createNetworkAcls(props *NetworkConstructProps) {
+	n.NetworkAcls = make(map[SubnetTier]awsec2.NetworkAcl)
+
+	tiers := []struct {
+		tier    SubnetTier
+		id      string
+		subnets *[]awsec2.ISubnet
+	}{
+		{SubnetTierPublic, "Public", n.PublicSubnets},
+		{SubnetTierPrivate, "Private", n.PrivateSubnets},
+		{SubnetTierIsolated, "Isolated", n.IsolatedSubnets},
+	}
+	for _, tier := range tiers {
+		acl := awsec2.NewNetworkAcl(n.Construct, jsii.String(tier.id+"Nacl"), &awsec2.NetworkAclProps{
+			Vpc:             n.Vpc,
+			NetworkAclName:  resourceName(props.Namer, ResourceGeneric, string(tier.tier)+"-nacl"),
+			SubnetSelection: &awsec2.SubnetSelection{Subnets: tier.subnets},
+		})
+
+		for _, rule := range DefaultNetworkAclRules[tier.tier] {
+			if rule.Https && !props.HttpsListener {
+				continue
+			}
+			switch rule.Peer {
+			case NaclPeerTransitGateway:
+				if props.TransitGateway != nil {
+					n.addCidrListEntries(acl, rule, "Transit", props.TransitGateway.DestinationCidrs)
+				}
+				continue
+			case NaclPeerBastionAllowlist:
+				n.addCidrListEntries(acl, rule, "Bastion", props.BastionAllowedCidrs)
+				continue
+			}
+
+			ipv4 := awsec2.AclCidr_AnyIpv4()
+			ipv6 := awsec2.AclCidr_AnyIpv6()
+			if rule.Peer == NaclPeerVpc {
+				ipv4 = awsec2.AclCidr_Ipv4(n.Vpc.VpcCidrBlock())
+				if n.DualStack {
+					ipv6 = awsec2.AclCidr_Ipv6(n.Ipv6CidrBlock)
+				}
+			}
+
+			id := fmt.Sprintf("%s%v", rule.Direction, rule.Number)
+			acl.AddEntry(jsii.String(id), &awsec2.CommonNetworkAclEntryOptions{
+				RuleNumber: jsii.Number(rule.Number),
+				Direction:  rule.Direction,
+				Cidr:       ipv4,
+				Traffic:    rule.traffic(),
+			})
+			if n.DualStack {
+				acl.AddEntry(jsii.String(id+"Ipv6"), &awsec2.CommonNetworkAclEntryOptions{
+					RuleNumber: jsii.Number(rule.Number + 1),
+					Direction:  rule.Direction,
+					Cidr:       ipv6,
+					Traffic:    rule.traffic(),
+				})
+			}
+		}
+
+		awscdk.Tags_Of(acl).Add(jsii.String("Name"), resourceName(props.Namer, ResourceGeneric, string(tier.tier)+"-nacl"), nil)
+		n.NetworkAcls[tier.tier] = acl
+	}
+}
+
+// addCidrListEntries expands a rule into one entry per CIDR of cidrs, IPv4 or
+// IPv6, numbered from the rule's Number
+func (n *NetworkConstruct) addCidrListEntries(acl awsec2.NetworkAcl, rule NaclRule, name string, cidrs []string) {
+	for i, cidr := range cidrs {
+		aclCidr := awsec2.AclCidr_Ipv4(jsii.String(cidr))
+		if strings.Contains(cidr, ":") {
+			aclCidr = awsec2.AclCidr_Ipv6(jsii.String(cidr))
+		}
+		acl.AddEntry(jsii.String(fmt.Sprintf("%s%v%s%d", rule.Direction, rule.Number, name, i+1)), &awsec2.CommonNetworkAclEntryOptions{
+			RuleNumber: jsii.Number(rule.Number + float64(i)),
+			Direction:  rule.Direction,
+			Cidr:       aclCidr,
+			Traffic:    rule.traffic(),
+		})
+	}
+}
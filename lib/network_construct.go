@@ -0,0 +1,434 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsec2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/customresources"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// ExistingVpcConfig imports a VPC owned by another team instead of creating one.
+type ExistingVpcConfig struct {
+	// VpcId selects the VPC by ID.
+	VpcId *string
+	// Tags selects the VPC by tags when VpcId is not set.
+	Tags map[string]string
+	// SubnetGroupNameTag is the subnet tag that names subnet groups.
+	// Defaults to aws-cdk:subnet-name.
+	SubnetGroupNameTag *string
+	// Subnet group names for each tier. When unset the tier is selected by
+	// subnet type (public, private with egress, isolated).
+	PublicSubnetGroupName   *string
+	PrivateSubnetGroupName  *string
+	IsolatedSubnetGroupName *string
+	// HasFlowLogs marks a VPC that already publishes flow logs, so the stack
+	// does not create its own.
+	HasFlowLogs bool
+}
+
+// Validate checks the VPC can be looked up.
+func (c *ExistingVpcConfig) Validate() error {
+	if c.VpcId == nil && len(c.Tags) == 0 {
+		return errors.New("ExistingVpc requires VpcId or Tags")
+	}
+	return nil
+}
+
+// NetworkConstructProps defines the inputs of the NetworkConstruct.
+type NetworkConstructProps struct {
+	// Namer builds resource names.
+	Namer Namer
+	// Network is the resolved VPC configuration. Only VpcEndpoints applies
+	// when ExistingVpc is set; an imported VPC keeps its own network ACLs.
+	Network NetworkConfig
+	// ExistingVpc imports the VPC with Vpc_FromLookup, which requires the
+	// stack to have an explicit account and region.
+	ExistingVpc *ExistingVpcConfig
+	// TransitGateway attaches a VPC created by this construct to a shared
+	// Transit Gateway.
+	TransitGateway *TransitGatewayConfig
+	// BastionAllowedCidrs may SSH into the public tier through its network
+	// ACL. Only set in BastionModeCidrAllowlist.
+	BastionAllowedCidrs []string
+	// HttpsListener opens the public tier's network ACL to HTTPS. Set when
+	// the ALB has an HTTPS listener.
+	HttpsListener bool
+}
+
+// NetworkConstruct is the VPC with public, private and isolated subnet tiers.
+type NetworkConstruct struct {
+	constructs.Construct
+	Vpc             awsec2.IVpc
+	PublicSubnets   *[]awsec2.ISubnet
+	PrivateSubnets  *[]awsec2.ISubnet
+	IsolatedSubnets *[]awsec2.ISubnet
+	// Imported is true when the VPC was looked up rather than created.
+	Imported bool
+	// NatSecurityGroup guards the NAT instances in NatModeInstance.
+	NatSecurityGroup awsec2.ISecurityGroup
+	// FirewallSubnets and NatSubnets are the /28 tiers holding the Network
+	// Firewall endpoints and the NAT gateways, only set when
+	// Network.NetworkFirewall is enabled. Both are ordered like PrivateSubnets.
+	FirewallSubnets *[]awsec2.ISubnet
+	NatSubnets      *[]awsec2.ISubnet
+	// TransitSubnets is the /28 tier holding the Transit Gateway attachment,
+	// only set when TransitGateway is configured.
+	TransitSubnets           *[]awsec2.ISubnet
+	TransitGatewayAttachment awsec2.CfnTransitGatewayAttachment
+	// NetworkAcls is keyed by tier, only set when Network.NetworkAcls is
+	// enabled on a VPC created by this construct.
+	NetworkAcls map[SubnetTier]awsec2.NetworkAcl
+	// Dual-stack resources, only set when Network.DualStack is enabled.
+	// Ipv6CidrBlock is the Amazon-provided /56 of the VPC.
+	DualStack                 bool
+	Ipv6CidrBlock             *string
+	EgressOnlyInternetGateway awsec2.CfnEgressOnlyInternetGateway
+	// VPC endpoints, only set when Network.VpcEndpoints is enabled.
+	// EndpointSecurityGroup admits HTTPS from the VPC to the interface
+	// endpoints and S3PrefixListId is the regional S3 managed prefix list.
+	EndpointSecurityGroup awsec2.SecurityGroup
+	GatewayEndpoints      map[string]awsec2.GatewayVpcEndpoint
+	InterfaceEndpoints    map[string]awsec2.InterfaceVpcEndpoint
+	S3PrefixListId        *string
+}
+
+// interfaceEndpointServices are the AWS APIs reached privately when VPC
+// endpoints are enabled, keyed by construct ID.
+var interfaceEndpointServices = []struct {
+	id      string
+	service func() awsec2.InterfaceVpcEndpointAwsService
+}{
+	{"SSM", awsec2.InterfaceVpcEndpointAwsService_SSM},
+	{"SSMMessages", awsec2.InterfaceVpcEndpointAwsService_SSM_MESSAGES},
+	{"EC2Messages", awsec2.InterfaceVpcEndpointAwsService_EC2_MESSAGES},
+	{"SecretsManager", awsec2.InterfaceVpcEndpointAwsService_SECRETS_MANAGER},
+	{"KMS", awsec2.InterfaceVpcEndpointAwsService_KMS},
+	{"CloudWatchLogs", awsec2.InterfaceVpcEndpointAwsService_CLOUDWATCH_LOGS},
+	{"STS", awsec2.InterfaceVpcEndpointAwsService_STS},
+	{"XRay", awsec2.InterfaceVpcEndpointAwsService_XRAY},
+}
+
+// NewNetworkConstruct creates the VPC with public/private/isolated subnets across
+// the configured AZs, or imports an existing one.
+func NewNetworkConstruct(scope constructs.Construct, id *string, props *NetworkConstructProps) *NetworkConstruct {
+	network := &NetworkConstruct{
+		Construct: constructs.NewConstruct(scope, id),
+	}
+
+	if props.ExistingVpc != nil {
+		network.importVpc(props.ExistingVpc)
+	} else {
+		network.createVpc(props)
+	}
+
+	if props.Network.VpcEndpoints != nil && *props.Network.VpcEndpoints {
+		network.createEndpoints(props)
+	}
+
+	return network
+}
+
+// createVpc creates the VPC and its three subnet tiers
+func (n *NetworkConstruct) createVpc(props *NetworkConstructProps) {
+	config := props.Network
+
+	// Without NAT the private tier cannot route to the internet, so CDK
+	// requires it to be declared isolated
+	privateSubnetType := awsec2.SubnetType_PRIVATE_WITH_EGRESS
+	natGateways := config.NatGateways
+	var natProvider awsec2.NatProvider
+	var natInstances awsec2.NatInstanceProvider
+	switch config.NatMode {
+	case NatModeSingle:
+		natGateways = jsii.Number(1)
+	case NatModeInstance:
+		natInstances = awsec2.NatProvider_Instance(&awsec2.NatInstanceProps{
+			InstanceType:          awsec2.NewInstanceType(config.NatInstanceType),
+			MachineImage:          natInstanceImage(),
+			DefaultAllowedTraffic: awsec2.NatTrafficDirection_OUTBOUND_ONLY,
+		})
+		natProvider = natInstances
+	case NatModeNone:
+		natGateways = jsii.Number(0)
+		privateSubnetType = awsec2.SubnetType_PRIVATE_ISOLATED
+	}
+
+	subnets := []*awsec2.SubnetConfiguration{
+		{
+			Name:       jsii.String("Public"),
+			SubnetType: awsec2.SubnetType_PUBLIC,
+			CidrMask:   config.SubnetCidrMask,
+		},
+		{
+			Name:       jsii.String("Private"),
+			SubnetType: privateSubnetType,
+			CidrMask:   config.SubnetCidrMask,
+		},
+		{
+			Name:       jsii.String("Isolated"),
+			SubnetType: awsec2.SubnetType_PRIVATE_ISOLATED,
+			CidrMask:   config.SubnetCidrMask,
+		},
+	}
+
+	// The NAT gateways get their own public tier so that only their subnets
+	// route return traffic through the firewall; the ALB keeps talking to the
+	// app tier directly. Both tiers come last, so enabling the firewall leaves
+	// the CIDRs of the other tiers unchanged.
+	var natSubnets *awsec2.SubnetSelection
+	firewall := config.NetworkFirewall != nil && *config.NetworkFirewall
+	if firewall {
+		subnets = append(subnets,
+			&awsec2.SubnetConfiguration{
+				Name:       jsii.String("Firewall"),
+				SubnetType: awsec2.SubnetType_PRIVATE_ISOLATED,
+				CidrMask:   jsii.Number(FirewallSubnetCidrMask),
+			},
+			&awsec2.SubnetConfiguration{
+				Name:       jsii.String("Nat"),
+				SubnetType: awsec2.SubnetType_PUBLIC,
+				CidrMask:   jsii.Number(FirewallSubnetCidrMask),
+			},
+		)
+		natSubnets = &awsec2.SubnetSelection{SubnetGroupName: jsii.String("Nat")}
+	}
+	if props.TransitGateway != nil {
+		subnets = append(subnets, &awsec2.SubnetConfiguration{
+			Name:       jsii.String("Transit"),
+			SubnetType: awsec2.SubnetType_PRIVATE_ISOLATED,
+			CidrMask:   jsii.Number(FirewallSubnetCidrMask),
+		})
+	}
+
+	vpc := awsec2.NewVpc(n.Construct, jsii.String("ProdVPC"), &awsec2.VpcProps{
+		VpcName:             resourceName(props.Namer, ResourceGeneric, "vpc"),
+		IpAddresses:         awsec2.IpAddresses_Cidr(config.VpcCidr),
+		MaxAzs:              config.MaxAzs,
+		NatGateways:         natGateways,
+		NatGatewayProvider:  natProvider,
+		NatGatewaySubnets:   natSubnets,
+		EnableDnsHostnames:  jsii.Bool(true),
+		EnableDnsSupport:    jsii.Bool(true),
+		SubnetConfiguration: &subnets,
+	})
+	n.Vpc = vpc
+
+	// Get subnet references by group name, as the private tier is isolated
+	// without NAT and the firewall adds a second public tier
+	n.PublicSubnets = vpc.SelectSubnets(&awsec2.SubnetSelection{SubnetGroupName: jsii.String("Public")}).Subnets
+	n.PrivateSubnets = vpc.SelectSubnets(&awsec2.SubnetSelection{SubnetGroupName: jsii.String("Private")}).Subnets
+	n.IsolatedSubnets = vpc.SelectSubnets(&awsec2.SubnetSelection{SubnetGroupName: jsii.String("Isolated")}).Subnets
+	if firewall {
+		n.FirewallSubnets = vpc.SelectSubnets(&awsec2.SubnetSelection{SubnetGroupName: jsii.String("Firewall")}).Subnets
+		n.NatSubnets = vpc.SelectSubnets(natSubnets).Subnets
+	}
+	if props.TransitGateway != nil {
+		n.TransitSubnets = vpc.SelectSubnets(&awsec2.SubnetSelection{SubnetGroupName: jsii.String("Transit")}).Subnets
+		n.attachTransitGateway(props)
+	}
+
+	// NAT instances only translate traffic that originates in the VPC
+	if natInstances != nil {
+		n.NatSecurityGroup = natInstances.SecurityGroup()
+		natInstances.Connections().AllowFrom(
+			awsec2.Peer_Ipv4(vpc.VpcCidrBlock()),
+			awsec2.Port_AllTraffic(),
+			jsii.String("All traffic from the VPC to the internet"),
+		)
+		awscdk.Tags_Of(n.NatSecurityGroup).Add(jsii.String("Name"), resourceName(props.Namer, ResourceGeneric, "nat-sg"), nil)
+	}
+
+	if config.DualStack != nil && *config.DualStack {
+		n.enableIpv6(vpc, config)
+	}
+
+	if config.NetworkAcls != nil && *config.NetworkAcls {
+		n.createNetworkAcls(props)
+	}
+
+	awscdk.Tags_Of(vpc).Add(jsii.String("Name"), resourceName(props.Namer, ResourceGeneric, "vpc"), nil)
+}
+
+// enableIpv6 adds an Amazon-provided IPv6 block to the VPC and a /64 to every
+// subnet. Public subnets route IPv6 through the internet gateway and the
+// private tier through an egress-only internet gateway; isolated subnets get
+// no IPv6 route, nor does the private tier in NatModeNone.
+func (n *NetworkConstruct) enableIpv6(vpc awsec2.Vpc, config NetworkConfig) {
+	ipv6Block := awsec2.NewCfnVPCCidrBlock(n.Construct, jsii.String("Ipv6CidrBlock"), &awsec2.CfnVPCCidrBlockProps{
+		VpcId:                       vpc.VpcId(),
+		AmazonProvidedIpv6CidrBlock: jsii.Bool(true),
+	})
+	n.DualStack = true
+	n.Ipv6CidrBlock = awscdk.Fn_Select(jsii.Number(0), vpc.VpcIpv6CidrBlocks())
+
+	subnets := append(append(append([]awsec2.ISubnet{}, *n.PublicSubnets...), *n.PrivateSubnets...), *n.IsolatedSubnets...)
+	subnetBlocks := awscdk.Fn_Cidr(n.Ipv6CidrBlock, jsii.Number(float64(len(subnets))), jsii.String("64"))
+	for i, subnet := range subnets {
+		cfnSubnet := subnet.Node().DefaultChild().(awsec2.CfnSubnet)
+		cfnSubnet.SetIpv6CidrBlock(awscdk.Fn_Select(jsii.Number(float64(i)), subnetBlocks))
+		cfnSubnet.SetAssignIpv6AddressOnCreation(jsii.Bool(true))
+		cfnSubnet.AddDependency(ipv6Block)
+	}
+
+	for i, subnet := range *n.PublicSubnets {
+		route := awsec2.NewCfnRoute(n.Construct, jsii.String(fmt.Sprintf("PublicIpv6Route%d", i+1)), &awsec2.CfnRouteProps{
+			RouteTableId:             subnet.RouteTable().RouteTableId(),
+			DestinationIpv6CidrBlock: jsii.String("::/0"),
+			GatewayId:                vpc.InternetGatewayId(),
+		})
+		// Like the IPv4 default route, wait for the gateway to be attached
+		route.Node().AddDependency(vpc.Node().FindChild(jsii.String("VPCGW")))
+	}
+
+	if config.NatMode == NatModeNone {
+		return
+	}
+	n.EgressOnlyInternetGateway = awsec2.NewCfnEgressOnlyInternetGateway(n.Construct, jsii.String("EgressOnlyIGW"), &awsec2.CfnEgressOnlyInternetGatewayProps{
+		VpcId: vpc.VpcId(),
+	})
+	for i, subnet := range *n.PrivateSubnets {
+		awsec2.NewCfnRoute(n.Construct, jsii.String(fmt.Sprintf("PrivateIpv6Route%d", i+1)), &awsec2.CfnRouteProps{
+			RouteTableId:                subnet.RouteTable().RouteTableId(),
+			DestinationIpv6CidrBlock:    jsii.String("::/0"),
+			EgressOnlyInternetGatewayId: n.EgressOnlyInternetGateway.Ref(),
+		})
+	}
+}
+
+// natInstanceImage is Amazon Linux 2023 for Graviton, configured at boot to
+// forward and masquerade traffic from the private subnets
+func natInstanceImage() awsec2.IMachineImage {
+	userData := awsec2.UserData_ForLinux(nil)
+	userData.AddCommands(
+		jsii.String("yum install -y iptables-services"),
+		jsii.String("systemctl enable --now iptables"),
+		jsii.String(`echo "net.ipv4.ip_forward=1" > /etc/sysctl.d/90-nat.conf`),
+		jsii.String("sysctl -p /etc/sysctl.d/90-nat.conf"),
+		jsii.String(`/sbin/iptables -t nat -A POSTROUTING -o "$(ip route show default | awk '{print $5}')" -j MASQUERADE`),
+		jsii.String("/sbin/iptables -F FORWARD"),
+		jsii.String("service iptables save"),
+	)
+
+	return awsec2.MachineImage_LatestAmazonLinux2023(&awsec2.AmazonLinux2023ImageSsmParameterProps{
+		CpuType:  awsec2.AmazonLinuxCpuType_ARM_64,
+		UserData: userData,
+	})
+}
+
+// importVpc looks up an existing VPC and maps its subnet groups to the tiers
+func (n *NetworkConstruct) importVpc(existing *ExistingVpcConfig) {
+	options := &awsec2.VpcLookupOptions{
+		VpcId:              existing.VpcId,
+		SubnetGroupNameTag: existing.SubnetGroupNameTag,
+	}
+	if len(existing.Tags) > 0 {
+		tags := make(map[string]*string, len(existing.Tags))
+		for key, value := range existing.Tags {
+			tags[key] = jsii.String(value)
+		}
+		options.Tags = &tags
+	}
+
+	n.Vpc = awsec2.Vpc_FromLookup(n.Construct, jsii.String("ProdVPC"), options)
+	n.Imported = true
+
+	n.PublicSubnets = n.subnetGroup(existing.PublicSubnetGroupName, n.Vpc.PublicSubnets())
+	n.PrivateSubnets = n.subnetGroup(existing.PrivateSubnetGroupName, n.Vpc.PrivateSubnets())
+	n.IsolatedSubnets = n.subnetGroup(existing.IsolatedSubnetGroupName, n.Vpc.IsolatedSubnets())
+}
+
+// subnetGroup returns the subnets of the named group, or fallback when no group is named
+func (n *NetworkConstruct) subnetGroup(name *string, fallback *[]awsec2.ISubnet) *[]awsec2.ISubnet {
+	if name == nil {
+		return fallback
+	}
+	return n.Vpc.SelectSubnets(&awsec2.SubnetSelection{
+		SubnetGroupName: name,
+	}).Subnets
+}
+
+// createEndpoints adds gateway and interface VPC endpoints so private workloads
+// reach AWS APIs without going through NAT
+func (n *NetworkConstruct) createEndpoints(props *NetworkConstructProps) {
+	n.GatewayEndpoints = make(map[string]awsec2.GatewayVpcEndpoint)
+	n.InterfaceEndpoints = make(map[string]awsec2.InterfaceVpcEndpoint)
+
+	// Gateway endpoints are route table entries for every non-public tier
+	gatewaySubnets := []*awsec2.SubnetSelection{{Subnets: n.PrivateSubnets}}
+	if len(*n.IsolatedSubnets) > 0 {
+		gatewaySubnets = append(gatewaySubnets, &awsec2.SubnetSelection{Subnets: n.IsolatedSubnets})
+	}
+	n.GatewayEndpoints["s3"] = awsec2.NewGatewayVpcEndpoint(n.Construct, jsii.String("S3Endpoint"), &awsec2.GatewayVpcEndpointProps{
+		Vpc:     n.Vpc,
+		Service: awsec2.GatewayVpcEndpointAwsService_S3(),
+		Subnets: &gatewaySubnets,
+	})
+	n.GatewayEndpoints["dynamodb"] = awsec2.NewGatewayVpcEndpoint(n.Construct, jsii.String("DynamoDBEndpoint"), &awsec2.GatewayVpcEndpointProps{
+		Vpc:     n.Vpc,
+		Service: awsec2.GatewayVpcEndpointAwsService_DYNAMODB(),
+		Subnets: &gatewaySubnets,
+	})
+
+	n.EndpointSecurityGroup = awsec2.NewSecurityGroup(n.Construct, jsii.String("EndpointSG"), &awsec2.SecurityGroupProps{
+		Vpc:              n.Vpc,
+		Description:      jsii.String("Security group for interface VPC endpoints"),
+		AllowAllOutbound: jsii.Bool(false),
+	})
+	n.EndpointSecurityGroup.AddIngressRule(
+		awsec2.Peer_Ipv4(n.Vpc.VpcCidrBlock()),
+		awsec2.Port_Tcp(jsii.Number(443)),
+		jsii.String("HTTPS from the VPC to AWS APIs"),
+		jsii.Bool(false),
+	)
+	if n.DualStack {
+		n.EndpointSecurityGroup.AddIngressRule(
+			awsec2.Peer_Ipv6(n.Ipv6CidrBlock),
+			awsec2.Port_Tcp(jsii.Number(443)),
+			jsii.String("HTTPS from the VPC to AWS APIs over IPv6"),
+			jsii.Bool(false),
+		)
+		// The VPC only reports its IPv6 block once the block is associated
+		n.EndpointSecurityGroup.Node().AddDependency(n.Node().FindChild(jsii.String("Ipv6CidrBlock")))
+	}
+	awscdk.Tags_Of(n.EndpointSecurityGroup).Add(jsii.String("Name"), resourceName(props.Namer, ResourceGeneric, "endpoint-sg"), nil)
+
+	for _, endpoint := range interfaceEndpointServices {
+		n.InterfaceEndpoints[endpoint.id] = awsec2.NewInterfaceVpcEndpoint(n.Construct, jsii.String(endpoint.id+"Endpoint"), &awsec2.InterfaceVpcEndpointProps{
+			Vpc:     n.Vpc,
+			Service: endpoint.service(),
+			Subnets: &awsec2.SubnetSelection{
+				Subnets: n.PrivateSubnets,
+			},
+			SecurityGroups:    &[]awsec2.ISecurityGroup{n.EndpointSecurityGroup},
+			PrivateDnsEnabled: jsii.Bool(true),
+			Open:              jsii.Bool(false),
+		})
+	}
+
+	// Security group rules can only target the S3 gateway endpoint through its
+	// managed prefix list, whose ID differs per region
+	region := awscdk.Stack_Of(n.Construct).Region()
+	prefixListName := fmt.Sprintf("com.amazonaws.%s.s3", *region)
+	prefixList := customresources.NewAwsCustomResource(n.Construct, jsii.String("S3PrefixList"), &customresources.AwsCustomResourceProps{
+		OnUpdate: &customresources.AwsSdkCall{
+			Service: jsii.String("EC2"),
+			Action:  jsii.String("describeManagedPrefixLists"),
+			Parameters: map[string]interface{}{
+				"Filters": []map[string]interface{}{
+					{"Name": "prefix-list-name", "Values": []string{prefixListName}},
+				},
+			},
+			PhysicalResourceId: customresources.PhysicalResourceId_Of(jsii.String(prefixListName)),
+			OutputPaths:        &[]*string{jsii.String("PrefixLists.0.PrefixListId")},
+		},
+		Policy: customresources.AwsCustomResourcePolicy_FromSdkCalls(&customresources.SdkCallsPolicyOptions{
+			Resources: customresources.AwsCustomResourcePolicy_ANY_RESOURCE(),
+		}),
+		InstallLatestAwsSdk: jsii.Bool(false),
+	})
+	n.S3PrefixListId = prefixList.GetResponseField(jsii.String("PrefixLists.0.PrefixListId"))
+}
@@ -0,0 +1,222 @@
+package lib
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsec2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsnetworkfirewall"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awss3"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// FirewallSubnetCidrMask is the prefix length of the firewall and NAT subnet
+// tiers, which only hold one endpoint or NAT gateway per AZ.
+const FirewallSubnetCidrMask = 28
+
+// firewallSubnetTierCount is the number of subnet groups the firewall adds
+// (firewall and NAT).
+const firewallSubnetTierCount = 2
+
+// The domain allowlist is one stateful rule group of fixed capacity; each
+// domain takes one rule per target type (TLS SNI and HTTP Host).
+const (
+	firewallRuleGroupCapacity = 100
+	maxFirewallDomains        = firewallRuleGroupCapacity / 2
+)
+
+// networkFirewallLogsPrefix is the logging bucket prefix alert and flow logs are written under.
+const networkFirewallLogsPrefix = "network-firewall"
+
+// FirewallDomainRule allows HTTP and HTTPS egress to a group of domains,
+// matched on the TLS SNI and the HTTP Host header.
+type FirewallDomainRule struct {
+	// Name says what the domains are for.
+	Name string
+	// Domains are exact names; a leading "." also matches every subdomain.
+	Domains []string
+}
+
+// DefaultFirewallDomainRules is the egress allowlist of the Network Firewall.
+// NetworkFirewallAllowedDomains are added to it; everything else leaving the
+// private tier is dropped.
+var DefaultFirewallDomainRules = []FirewallDomainRule{
+	{Name: "AWS APIs and Amazon Linux repositories", Domains: []string{".amazonaws.com"}},
+}
+
+// NetworkFirewallConstructProps defines the inputs of the NetworkFirewallConstruct.
+type NetworkFirewallConstructProps struct {
+	// Namer builds resource names.
+	Namer Namer
+	Vpc   awsec2.IVpc
+	// FirewallSubnets get one firewall endpoint each. PrivateSubnets send
+	// their default route to the endpoint in their AZ, and NatSubnets route
+	// return traffic to the private tier back through it.
+	FirewallSubnets *[]awsec2.ISubnet
+	PrivateSubnets  *[]awsec2.ISubnet
+	NatSubnets      *[]awsec2.ISubnet
+	// AllowedDomains are added to DefaultFirewallDomainRules, e.g. package mirrors.
+	AllowedDomains []string
+	// LogBucket receives the alert and flow logs.
+	LogBucket awss3.IBucket
+}
+
+// NetworkFirewallConstruct inspects the internet egress of the private tier
+// with AWS Network Firewall. Traffic flows private subnet -> firewall endpoint
+// -> NAT gateway -> internet gateway, and back the same way.
+type NetworkFirewallConstruct struct {
+	constructs.Construct
+	RuleGroup awsnetworkfirewall.CfnRuleGroup
+	Policy    awsnetworkfirewall.CfnFirewallPolicy
+	// Firewalls has one firewall per AZ, in the order of PrivateSubnets. A
+	// multi-AZ firewall reports its endpoints in no particular order, so a
+	// route could not name the endpoint of its own AZ; endpoints are billed
+	// the same either way.
+	Firewalls []awsnetworkfirewall.CfnFirewall
+	// EndpointIds are the firewall endpoints, in the order of PrivateSubnets.
+	EndpointIds []*string
+}
+
+// NewNetworkFirewallConstruct creates the firewall policy, the per-AZ
+// firewalls and their logging, and reroutes the private tier through them.
+func NewNetworkFirewallConstruct(scope constructs.Construct, id *string, props *NetworkFirewallConstructProps) *NetworkFirewallConstruct {
+	firewall := &NetworkFirewallConstruct{
+		Construct: constructs.NewConstruct(scope, id),
+	}
+
+	firewall.createPolicy(props)
+	firewall.createFirewalls(props)
+	firewall.routeThroughFirewalls(props)
+
+	return firewall
+}
+
+// firewallDomains flattens DefaultFirewallDomainRules and the allowed domains
+func firewallDomains(allowed []string) []string {
+	var domains []string
+	for _, rule := range DefaultFirewallDomainRules {
+		domains = append(domains, rule.Domains...)
+	}
+	return append(domains, allowed...)
+}
+
+// createPolicy creates the domain allowlist and a strict-order policy that
+// drops every established flow it does not allow
+func (f *NetworkFirewallConstruct) createPolicy(props *NetworkFirewallConstructProps) {
+	// A single allowlist group: each allowlist group drops the HTTP/TLS traffic
+	// it does not match, so a second group would never see its domains
+	f.RuleGroup = awsnetworkfirewall.NewCfnRuleGroup(f.Construct, jsii.String("EgressAllowlist"), &awsnetworkfirewall.CfnRuleGroupProps{
+		RuleGroupName: resourceName(props.Namer, ResourceGeneric, "egress-allowlist"),
+		Description:   jsii.String("Domains the private tier may reach over HTTP and HTTPS"),
+		Type:          jsii.String("STATEFUL"),
+		Capacity:      jsii.Number(firewallRuleGroupCapacity),
+		RuleGroup: &awsnetworkfirewall.CfnRuleGroup_RuleGroupProperty{
+			RulesSource: &awsnetworkfirewall.CfnRuleGroup_RulesSourceProperty{
+				RulesSourceList: &awsnetworkfirewall.CfnRuleGroup_RulesSourceListProperty{
+					GeneratedRulesType: jsii.String("ALLOWLIST"),
+					TargetTypes:        jsii.Strings("TLS_SNI", "HTTP_HOST"),
+					Targets:            jsii.Strings(firewallDomains(props.AllowedDomains)...),
+				},
+			},
+			StatefulRuleOptions: &awsnetworkfirewall.CfnRuleGroup_StatefulRuleOptionsProperty{
+				RuleOrder: jsii.String("STRICT_ORDER"),
+			},
+		},
+	})
+
+	// drop_established rather than drop_strict lets the TLS handshake reach
+	// the point where the SNI can be matched
+	f.Policy = awsnetworkfirewall.NewCfnFirewallPolicy(f.Construct, jsii.String("FirewallPolicy"), &awsnetworkfirewall.CfnFirewallPolicyProps{
+		FirewallPolicyName: resourceName(props.Namer, ResourceGeneric, "firewall-policy"),
+		Description:        jsii.String("Egress inspection of " + props.Namer.Prefix()),
+		FirewallPolicy: &awsnetworkfirewall.CfnFirewallPolicy_FirewallPolicyProperty{
+			StatelessDefaultActions:         jsii.Strings("aws:forward_to_sfe"),
+			StatelessFragmentDefaultActions: jsii.Strings("aws:forward_to_sfe"),
+			StatefulRuleGroupReferences: &[]interface{}{
+				&awsnetworkfirewall.CfnFirewallPolicy_StatefulRuleGroupReferenceProperty{
+					ResourceArn: f.RuleGroup.AttrRuleGroupArn(),
+					Priority:    jsii.Number(100),
+				},
+			},
+			StatefulEngineOptions: &awsnetworkfirewall.CfnFirewallPolicy_StatefulEngineOptionsProperty{
+				RuleOrder: jsii.String("STRICT_ORDER"),
+			},
+			StatefulDefaultActions: jsii.Strings("aws:drop_established", "aws:alert_established"),
+		},
+	})
+}
+
+// createFirewalls creates one firewall per firewall subnet and sends its
+// alert and flow logs to the logging bucket
+func (f *NetworkFirewallConstruct) createFirewalls(props *NetworkFirewallConstructProps) {
+	allowLogDelivery(props.LogBucket, networkFirewallLogsPrefix)
+
+	for i, subnet := range *props.FirewallSubnets {
+		name := resourceName(props.Namer, ResourceGeneric, fmt.Sprintf("firewall-%d", i+1))
+		firewall := awsnetworkfirewall.NewCfnFirewall(f.Construct, jsii.String(fmt.Sprintf("Firewall%d", i+1)), &awsnetworkfirewall.CfnFirewallProps{
+			FirewallName:      name,
+			FirewallPolicyArn: f.Policy.AttrFirewallPolicyArn(),
+			VpcId:             props.Vpc.VpcId(),
+			SubnetMappings: &[]interface{}{
+				&awsnetworkfirewall.CfnFirewall_SubnetMappingProperty{SubnetId: subnet.SubnetId()},
+			},
+		})
+		awscdk.Tags_Of(firewall).Add(jsii.String("Name"), name, nil)
+
+		logDestination := &map[string]*string{
+			"bucketName": props.LogBucket.BucketName(),
+			"prefix":     jsii.String(networkFirewallLogsPrefix),
+		}
+		awsnetworkfirewall.NewCfnLoggingConfiguration(f.Construct, jsii.String(fmt.Sprintf("Firewall%dLogging", i+1)), &awsnetworkfirewall.CfnLoggingConfigurationProps{
+			FirewallArn: firewall.AttrFirewallArn(),
+			LoggingConfiguration: &awsnetworkfirewall.CfnLoggingConfiguration_LoggingConfigurationProperty{
+				LogDestinationConfigs: &[]interface{}{
+					&awsnetworkfirewall.CfnLoggingConfiguration_LogDestinationConfigProperty{
+						LogType:            jsii.String("ALERT"),
+						LogDestinationType: jsii.String("S3"),
+						LogDestination:     logDestination,
+					},
+					&awsnetworkfirewall.CfnLoggingConfiguration_LogDestinationConfigProperty{
+						LogType:            jsii.String("FLOW"),
+						LogDestinationType: jsii.String("S3"),
+						LogDestination:     logDestination,
+					},
+				},
+			},
+		})
+
+		// EndpointIds holds the single "<az>:<vpce-id>" of this firewall
+		endpoint := awscdk.Fn_Select(jsii.Number(0), firewall.AttrEndpointIds())
+		f.Firewalls = append(f.Firewalls, firewall)
+		f.EndpointIds = append(f.EndpointIds, awscdk.Fn_Select(jsii.Number(1), awscdk.Fn_Split(jsii.String(":"), endpoint, nil)))
+	}
+}
+
+// routeThroughFirewalls points the private tier's default route at the
+// firewall endpoint of its AZ, moves the NAT gateway route to the firewall
+// subnet, and routes return traffic from the NAT subnets back through the
+// endpoint so the firewall sees both directions of every flow
+func (f *NetworkFirewallConstruct) routeThroughFirewalls(props *NetworkFirewallConstructProps) {
+	for i, private := range *props.PrivateSubnets {
+		defaultRoute := private.Node().FindChild(jsii.String("DefaultRoute")).(awsec2.CfnRoute)
+		natGatewayId := defaultRoute.NatGatewayId()
+		endpointId := f.EndpointIds[i]
+
+		awsec2.NewCfnRoute(f.Construct, jsii.String(fmt.Sprintf("FirewallSubnet%dDefaultRoute", i+1)), &awsec2.CfnRouteProps{
+			RouteTableId:         (*props.FirewallSubnets)[i].RouteTable().RouteTableId(),
+			DestinationCidrBlock: jsii.String("0.0.0.0/0"),
+			NatGatewayId:         natGatewayId,
+		})
+		defaultRoute.SetNatGatewayId(nil)
+		defaultRoute.SetVpcEndpointId(endpointId)
+
+		for j, nat := range *props.NatSubnets {
+			awsec2.NewCfnRoute(f.Construct, jsii.String(fmt.Sprintf("NatSubnet%dToPrivate%dRoute", j+1, i+1)), &awsec2.CfnRouteProps{
+				RouteTableId:         nat.RouteTable().RouteTableId(),
+				DestinationCidrBlock: private.Ipv4CidrBlock(),
+				VpcEndpointId:        endpointId,
+			})
+		}
+	}
+}
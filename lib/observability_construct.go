@@ -0,0 +1,148 @@
+package lib
+
+import (
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awscloudtrail"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awscloudwatch"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awscloudwatchactions"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsec2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awskms"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslambda"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslogs"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsroute53resolver"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awss3"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awssns"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// ObservabilityConstructProps defines the inputs of the ObservabilityConstruct.
+type ObservabilityConstructProps struct {
+	// Namer builds resource names.
+	Namer Namer
+	// Vpc has its traffic captured by VPC Flow Logs.
+	Vpc    awsec2.IVpc
+	KmsKey awskms.IKey
+	// TrailBucket receives the CloudTrail log files.
+	TrailBucket awss3.IBucket
+	// LambdaFunction is alarmed on errors.
+	LambdaFunction awslambda.IFunction
+	LogRetention   awslogs.RetentionDays
+	RemovalPolicy  awscdk.RemovalPolicy
+	// SkipFlowLogs leaves flow logs to whoever owns an imported VPC.
+	SkipFlowLogs bool
+	// FlowLogsCloudWatch also delivers the flow logs to a log group kept for LogRetention.
+	FlowLogsCloudWatch bool
+	// ResolverQueryLogs sends the VPC's DNS queries to LogBucket or a log group.
+	ResolverQueryLogs QueryLogDestination
+	LogBucket         awss3.IBucket
+	// DnsFirewall blocks DnsFirewallBlockedDomains except DnsFirewallAllowedDomains.
+	DnsFirewall               bool
+	DnsFirewallBlockedDomains []string
+	DnsFirewallAllowedDomains []string
+}
+
+// ObservabilityConstruct holds VPC Flow Logs, Resolver query logs, the DNS
+// Firewall, CloudTrail, the security alerts topic and the CloudWatch alarms.
+type ObservabilityConstruct struct {
+	constructs.Construct
+	// FlowLogsBucket is nil when SkipFlowLogs is set; FlowLogGroup is only
+	// set with FlowLogsCloudWatch.
+	FlowLogsBucket awss3.Bucket
+	FlowLogGroup   awslogs.LogGroup
+	AlertsTopic    awssns.Topic
+	Trail          awscloudtrail.Trail
+	// QueryLogConfig is nil unless ResolverQueryLogs is set; QueryLogGroup is
+	// only set for the cloudwatch destination.
+	QueryLogConfig awsroute53resolver.CfnResolverQueryLoggingConfig
+	QueryLogGroup  awslogs.LogGroup
+	// DnsFirewallRuleGroup and DnsFirewallAlarm are nil unless DnsFirewall is set.
+	DnsFirewallRuleGroup awsroute53resolver.CfnFirewallRuleGroup
+	DnsFirewallAlarm     awscloudwatch.Alarm
+}
+
+// NewObservabilityConstruct creates flow logs, the audit trail and alarms.
+func NewObservabilityConstruct(scope constructs.Construct, id *string, props *ObservabilityConstructProps) *ObservabilityConstruct {
+	observability := &ObservabilityConstruct{
+		Construct: constructs.NewConstruct(scope, id),
+	}
+
+	observability.createFlowLogs(props)
+	observability.createSNSAlerts(props)
+	observability.createMonitoring(props)
+	observability.createResolverQueryLogging(props)
+	observability.createDnsFirewall(props)
+
+	return observability
+}
+
+// createSNSAlerts creates SNS topic for security alerts
+func (o *ObservabilityConstruct) createSNSAlerts(props *ObservabilityConstructProps) {
+	o.AlertsTopic = awssns.NewTopic(o.Construct, jsii.String("ProdSecurityAlerts"), &awssns.TopicProps{
+		TopicName:   resourceName(props.Namer, ResourceGeneric, "security-alerts"),
+		DisplayName: jsii.String("Production Security Alerts"),
+	})
+
+	awscdk.Tags_Of(o.AlertsTopic).Add(jsii.String("Name"), resourceName(props.Namer, ResourceGeneric, "security-alerts"), nil)
+}
+
+// createMonitoring creates CloudTrail for compliance and monitoring
+func (o *ObservabilityConstruct) createMonitoring(props *ObservabilityConstructProps) {
+	trailLogGroup := awslogs.NewLogGroup(o.Construct, jsii.String("CloudTrailLogGroup"), &awslogs.LogGroupProps{
+		LogGroupName:  jsii.String("/aws/cloudtrail/" + props.Namer.Prefix()),
+		EncryptionKey: props.KmsKey,
+		Retention:     props.LogRetention,
+		RemovalPolicy: props.RemovalPolicy,
+	})
+
+	// Create CloudTrail
+	o.Trail = awscloudtrail.NewTrail(o.Construct, jsii.String("ProdCloudTrail"), &awscloudtrail.TrailProps{
+		TrailName:                  trailName(props.Namer),
+		Bucket:                     props.TrailBucket,
+		S3KeyPrefix:                jsii.String(cloudTrailLogsPrefix + "/"),
+		IncludeGlobalServiceEvents: jsii.Bool(true),
+		IsMultiRegionTrail:         jsii.Bool(true),
+		EnableFileValidation:       jsii.Bool(true),
+		EncryptionKey:              props.KmsKey,
+		SendToCloudWatchLogs:       jsii.Bool(true),
+		CloudWatchLogGroup:         trailLogGroup,
+	})
+
+	// Create CloudWatch Alarms for monitoring
+	awscloudwatch.NewAlarm(o.Construct, jsii.String("LambdaErrorAlarm"), &awscloudwatch.AlarmProps{
+		AlarmName:         resourceName(props.Namer, ResourceGeneric, "lambda-errors"),
+		AlarmDescription:  jsii.String("Lambda function error rate"),
+		Metric:            props.LambdaFunction.MetricErrors(nil),
+		Threshold:         jsii.Number(1),
+		EvaluationPeriods: jsii.Number(2),
+		TreatMissingData:  awscloudwatch.TreatMissingData_NOT_BREACHING,
+	})
+
+	// Alert when Secrets Manager reports a failed rotation
+	rotationFailures := trailLogGroup.AddMetricFilter(jsii.String("SecretRotationFailedFilter"), &awslogs.MetricFilterOptions{
+		FilterName: resourceName(props.Namer, ResourceGeneric, "secret-rotation-failed"),
+		FilterPattern: awslogs.FilterPattern_Literal(jsii.String(
+			`{ ($.eventSource = "secretsmanager.amazonaws.com") && ($.eventName = "RotationFailed") }`,
+		)),
+		MetricNamespace: jsii.String(props.Namer.Prefix() + "/SecretsManager"),
+		MetricName:      jsii.String("RotationFailed"),
+		MetricValue:     jsii.String("1"),
+		DefaultValue:    jsii.Number(0),
+	})
+
+	rotationAlarm := awscloudwatch.NewAlarm(o.Construct, jsii.String("SecretRotationFailedAlarm"), &awscloudwatch.AlarmProps{
+		AlarmName:        resourceName(props.Namer, ResourceGeneric, "secret-rotation-failed"),
+		AlarmDescription: jsii.String("Secrets Manager rotation failed for the application secret"),
+		Metric: rotationFailures.Metric(&awscloudwatch.MetricOptions{
+			Statistic: jsii.String("Sum"),
+			Period:    awscdk.Duration_Minutes(jsii.Number(5)),
+		}),
+		Threshold:          jsii.Number(1),
+		EvaluationPeriods:  jsii.Number(1),
+		ComparisonOperator: awscloudwatch.ComparisonOperator_GREATER_THAN_OR_EQUAL_TO_THRESHOLD,
+		TreatMissingData:   awscloudwatch.TreatMissingData_NOT_BREACHING,
+	})
+	rotationAlarm.AddAlarmAction(awscloudwatchactions.NewSnsAction(o.AlertsTopic))
+
+	awscdk.Tags_Of(o.Trail).Add(jsii.String("Name"), resourceName(props.Namer, ResourceGeneric, "cloudtrail"), nil)
+}
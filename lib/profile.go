@@ -0,0 +1,63 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// LoadProfile reads the <name>.json environment profile from dir. Unknown keys
+// are rejected. A missing profile yields an empty configuration so ad-hoc
+// environments (e.g. per-PR suffixes) fall back to DefaultStackConfig.
+func LoadProfile(dir, name string) (StackConfig, error) {
+	if name == "" || name != filepath.Base(name) {
+		return StackConfig{}, fmt.Errorf("invalid profile name %q", name)
+	}
+
+	path := filepath.Join(dir, name+".json")
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return StackConfig{}, nil
+	}
+	if err != nil {
+		return StackConfig{}, fmt.Errorf("reading profile %s: %w", path, err)
+	}
+
+	return ParseProfile(path, data)
+}
+
+// ParseProfile decodes a JSON environment profile, rejecting unknown keys.
+// The source is only used in error messages.
+func ParseProfile(source string, data []byte) (StackConfig, error) {
+	var profile StackConfig
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&profile); err != nil {
+		return StackConfig{}, fmt.Errorf("parsing profile %s: %w", source, err)
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return StackConfig{}, fmt.Errorf("parsing profile %s: unexpected data after the profile object", source)
+	}
+
+	return profile, nil
+}
+
+// ApplyProfile fills every configuration setting the props leave unset from
+// the profile. Settings already present on the props take precedence.
+func (p *TapStackProps) ApplyProfile(profile StackConfig) {
+	merged := profile
+	merged.merge(p.explicitConfig())
+
+	p.Network = &merged.Network
+	p.Compute = &merged.Compute
+	p.Lambda = &merged.Lambda
+	p.Cdn = &merged.Cdn
+	p.Logging = &merged.Logging
+	p.SecretRotationDays = merged.SecretRotationDays
+	p.RemovalPolicy = merged.RemovalPolicy
+}
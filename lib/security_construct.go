@@ -0,0 +1,486 @@
+package lib
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsec2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awskms"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslambda"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslogs"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsrds"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awssecretsmanager"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsssm"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// SecurityConstructProps defines the inputs of the SecurityConstruct.
+type SecurityConstructProps struct {
+	// Namer builds resource names.
+	Namer             Namer
+	EnvironmentSuffix *string
+	Vpc               awsec2.IVpc
+	// PrivateSubnets host the secret rotation Lambda.
+	PrivateSubnets *[]awsec2.ISubnet
+	// DatabaseEngine adds the database security group and selects the hosted
+	// rotation for the application secret. DatabaseEngineNone skips both.
+	DatabaseEngine     DatabaseEngine
+	SecretRotationDays *float64
+	LogRetention       awslogs.RetentionDays
+	RemovalPolicy      awscdk.RemovalPolicy
+	// DualStack adds IPv6 rules alongside the IPv4 ones.
+	DualStack bool
+	// HttpsListener opens the ALB security group to HTTPS. Set when the ALB
+	// has an HTTPS listener; otherwise only HTTP is accepted.
+	HttpsListener bool
+	// BastionMode decides whether the bastion security group exists and
+	// whether it accepts SSH from BastionAllowedCidrs.
+	BastionMode         BastionMode
+	BastionAllowedCidrs []string
+	// InstanceConnectEndpoint adds the endpoint security group and makes it the
+	// only SSH source of the EC2 instances.
+	InstanceConnectEndpoint bool
+	// EndpointSecurityGroup and S3PrefixListId come from the VPC endpoints.
+	// When set, Lambda egress is limited to them instead of any HTTPS host.
+	EndpointSecurityGroup awsec2.ISecurityGroup
+	S3PrefixListId        *string
+	// ExistingKmsKeyArn imports a customer-managed key instead of creating one.
+	// Its key policy is owned by the key's account and is not modified here.
+	ExistingKmsKeyArn *string
+	// KmsKeyPerDomain creates a key for each of KeyDomains instead of a single
+	// key. It cannot be combined with ExistingKmsKeyArn.
+	KmsKeyPerDomain bool
+}
+
+// SecurityConstruct holds the customer-managed KMS keys, security groups,
+// the rotated application secret and the SSM configuration parameters.
+type SecurityConstruct struct {
+	constructs.Construct
+	// KmsKey is the app data key, the only key unless KmsKeyPerDomain is set.
+	KmsKey  awskms.IKey
+	KmsKeys KmsKeys
+	// keys creates the on-demand domain keys; nil for an imported key
+	keys *keyFactory
+	// SecurityGroups is keyed by tier: lambda, ec2, alb, bastion, eice and db.
+	// bastion is absent in BastionModeNone, eice without InstanceConnectEndpoint.
+	SecurityGroups map[string]awsec2.SecurityGroup
+	Secret         awssecretsmanager.Secret
+	SSMParameters  map[string]awsssm.StringParameter
+}
+
+// NewSecurityConstruct creates the key, security groups, secret and parameters.
+func NewSecurityConstruct(scope constructs.Construct, id *string, props *SecurityConstructProps) *SecurityConstruct {
+	security := &SecurityConstruct{
+		Construct:      constructs.NewConstruct(scope, id),
+		SecurityGroups: make(map[string]awsec2.SecurityGroup),
+		SSMParameters:  make(map[string]awsssm.StringParameter),
+	}
+
+	security.createKMSKey(props)
+	security.createSecurityGroups(props)
+	security.createSecret(props)
+	security.createSSMParameters(props)
+	security.createSecretRotation(props)
+
+	return security
+}
+
+// createKMSKey imports the existing key or creates the customer-managed keys,
+// one shared key or one per data domain
+func (s *SecurityConstruct) createKMSKey(props *SecurityConstructProps) {
+	if props.ExistingKmsKeyArn != nil {
+		s.KmsKeys = sharedKmsKeys(awskms.Key_FromKeyArn(s.Construct, jsii.String("ProdKMSKey"), props.ExistingKmsKeyArn))
+	} else {
+		factory := keyFactory{scope: s.Construct, namer: props.Namer, removalPolicy: props.RemovalPolicy}
+		s.keys = &factory
+		if props.KmsKeyPerDomain {
+			s.KmsKeys = factory.perDomain()
+		} else {
+			s.KmsKeys = factory.shared()
+		}
+	}
+	s.KmsKey = s.KmsKeys[KeyDomainAppData]
+}
+
+// createSecurityGroups creates security groups with least privilege access
+func (s *SecurityConstruct) createSecurityGroups(props *SecurityConstructProps) {
+	// Lambda security group - AWS API calls stay on the VPC endpoints when they exist
+	privateAPIs := props.EndpointSecurityGroup != nil
+	lambdaSG := awsec2.NewSecurityGroup(s.Construct, jsii.String("LambdaSG"), &awsec2.SecurityGroupProps{
+		Vpc:                  props.Vpc,
+		Description:          jsii.String("Security group for Lambda functions"),
+		AllowAllOutbound:     jsii.Bool(!privateAPIs),
+		AllowAllIpv6Outbound: jsii.Bool(props.DualStack && !privateAPIs),
+	})
+	if privateAPIs {
+		lambdaSG.Connections().AllowTo(
+			props.EndpointSecurityGroup,
+			awsec2.Port_Tcp(jsii.Number(443)),
+			jsii.String("HTTPS to the interface VPC endpoints"),
+		)
+		lambdaSG.AddEgressRule(
+			awsec2.Peer_PrefixList(props.S3PrefixListId),
+			awsec2.Port_Tcp(jsii.Number(443)),
+			jsii.String("HTTPS to S3 through the gateway endpoint"),
+			jsii.Bool(false),
+		)
+	} else {
+		lambdaSG.AddEgressRule(
+			awsec2.Peer_AnyIpv4(),
+			awsec2.Port_Tcp(jsii.Number(443)),
+			jsii.String("HTTPS outbound for AWS API calls"),
+			jsii.Bool(false),
+		)
+	}
+	s.SecurityGroups["lambda"] = lambdaSG
+
+	// EC2 security group (private subnets only)
+	ec2SG := awsec2.NewSecurityGroup(s.Construct, jsii.String("EC2SG"), &awsec2.SecurityGroupProps{
+		Vpc:                  props.Vpc,
+		Description:          jsii.String("Security group for EC2 instances in private subnets"),
+		AllowAllIpv6Outbound: jsii.Bool(props.DualStack),
+	})
+	s.SecurityGroups["ec2"] = ec2SG
+
+	// Application Load Balancer security group (public subnets)
+	albSG := awsec2.NewSecurityGroup(s.Construct, jsii.String("ALBSG"), &awsec2.SecurityGroupProps{
+		Vpc:              props.Vpc,
+		Description:      jsii.String("Security group for the internet-facing Application Load Balancer"),
+		AllowAllOutbound: jsii.Bool(false),
+	})
+	httpDescription := "HTTP from the internet"
+	if props.HttpsListener {
+		httpDescription = "HTTP from the internet (redirected to HTTPS)"
+		albSG.AddIngressRule(
+			awsec2.Peer_AnyIpv4(),
+			awsec2.Port_Tcp(jsii.Number(443)),
+			jsii.String("HTTPS from the internet"),
+			jsii.Bool(false),
+		)
+		if props.DualStack {
+			albSG.AddIngressRule(
+				awsec2.Peer_AnyIpv6(),
+				awsec2.Port_Tcp(jsii.Number(443)),
+				jsii.String("HTTPS from the internet over IPv6"),
+				jsii.Bool(false),
+			)
+		}
+	}
+	albSG.AddIngressRule(
+		awsec2.Peer_AnyIpv4(),
+		awsec2.Port_Tcp(jsii.Number(80)),
+		jsii.String(httpDescription),
+		jsii.Bool(false),
+	)
+	if props.DualStack {
+		albSG.AddIngressRule(
+			awsec2.Peer_AnyIpv6(),
+			awsec2.Port_Tcp(jsii.Number(80)),
+			jsii.String(httpDescription+" over IPv6"),
+			jsii.Bool(false),
+		)
+	}
+	s.SecurityGroups["alb"] = albSG
+
+	// Only the ALB may reach the application port on EC2 instances
+	albSG.Connections().AllowTo(
+		ec2SG,
+		awsec2.Port_Tcp(jsii.Number(80)),
+		jsii.String("HTTP from the Application Load Balancer"),
+	)
+
+	// Bastion host security group - SSH is only opened in cidr-allowlist mode,
+	// Session Manager needs no ingress at all
+	var bastionSG awsec2.SecurityGroup
+	switch props.BastionMode {
+	case BastionModeSsm:
+		bastionSG = awsec2.NewSecurityGroup(s.Construct, jsii.String("BastionSG"), &awsec2.SecurityGroupProps{
+			Vpc:                  props.Vpc,
+			Description:          jsii.String("Security group for the Session Manager bastion host (no inbound access)"),
+			AllowAllIpv6Outbound: jsii.Bool(props.DualStack),
+		})
+	case BastionModeCidrAllowlist:
+		bastionSG = awsec2.NewSecurityGroup(s.Construct, jsii.String("BastionSG"), &awsec2.SecurityGroupProps{
+			Vpc:                  props.Vpc,
+			Description:          jsii.String("Security group for bastion host SSH access"),
+			AllowAllIpv6Outbound: jsii.Bool(props.DualStack),
+		})
+		for _, cidr := range props.BastionAllowedCidrs {
+			var peer awsec2.IPeer
+			if strings.Contains(cidr, ":") {
+				peer = awsec2.Peer_Ipv6(jsii.String(cidr))
+			} else {
+				peer = awsec2.Peer_Ipv4(jsii.String(cidr))
+			}
+			bastionSG.AddIngressRule(
+				peer,
+				awsec2.Port_Tcp(jsii.Number(22)),
+				jsii.String("SSH access from "+cidr),
+				jsii.Bool(false),
+			)
+		}
+
+		// Allow bastion to SSH to EC2 instances, unless the endpoint replaces the hop
+		if !props.InstanceConnectEndpoint {
+			ec2SG.AddIngressRule(
+				awsec2.Peer_SecurityGroupId(bastionSG.SecurityGroupId(), nil),
+				awsec2.Port_Tcp(jsii.Number(22)),
+				jsii.String("SSH from bastion host"),
+				jsii.Bool(false),
+			)
+		}
+	}
+	if bastionSG != nil {
+		s.SecurityGroups["bastion"] = bastionSG
+	}
+
+	// EC2 Instance Connect Endpoint security group - it only forwards SSH to the instances
+	var eiceSG awsec2.SecurityGroup
+	if props.InstanceConnectEndpoint {
+		eiceSG = awsec2.NewSecurityGroup(s.Construct, jsii.String("InstanceConnectEndpointSG"), &awsec2.SecurityGroupProps{
+			Vpc:              props.Vpc,
+			Description:      jsii.String("Security group for the EC2 Instance Connect Endpoint"),
+			AllowAllOutbound: jsii.Bool(false),
+		})
+		ec2SG.Connections().AllowFrom(
+			eiceSG,
+			awsec2.Port_Tcp(jsii.Number(22)),
+			jsii.String("SSH from the EC2 Instance Connect Endpoint"),
+		)
+		s.SecurityGroups["eice"] = eiceSG
+	}
+
+	// Database security group (isolated subnets) - only the data tier is enabled
+	var dbSG awsec2.SecurityGroup
+	if props.DatabaseEngine != DatabaseEngineNone {
+		dbSG = awsec2.NewSecurityGroup(s.Construct, jsii.String("DatabaseSG"), &awsec2.SecurityGroupProps{
+			Vpc:              props.Vpc,
+			Description:      jsii.String("Security group for the Aurora cluster in isolated subnets"),
+			AllowAllOutbound: jsii.Bool(false),
+		})
+		dbPort := awsec2.Port_Tcp(jsii.Number(props.DatabaseEngine.Port()))
+		dbSG.Connections().AllowFrom(ec2SG, dbPort, jsii.String("Database access from EC2 instances"))
+		dbSG.Connections().AllowFrom(lambdaSG, dbPort, jsii.String("Database access from Lambda functions"))
+		s.SecurityGroups["db"] = dbSG
+	}
+
+	// Tag security groups
+	awscdk.Tags_Of(lambdaSG).Add(jsii.String("Name"), resourceName(props.Namer, ResourceGeneric, "lambda-sg"), nil)
+	awscdk.Tags_Of(ec2SG).Add(jsii.String("Name"), resourceName(props.Namer, ResourceGeneric, "ec2-sg"), nil)
+	awscdk.Tags_Of(albSG).Add(jsii.String("Name"), resourceName(props.Namer, ResourceGeneric, "alb-sg"), nil)
+	if bastionSG != nil {
+		awscdk.Tags_Of(bastionSG).Add(jsii.String("Name"), resourceName(props.Namer, ResourceGeneric, "bastion-sg"), nil)
+	}
+	if eiceSG != nil {
+		awscdk.Tags_Of(eiceSG).Add(jsii.String("Name"), resourceName(props.Namer, ResourceGeneric, "eice-sg"), nil)
+	}
+	if dbSG != nil {
+		awscdk.Tags_Of(dbSG).Add(jsii.String("Name"), resourceName(props.Namer, ResourceGeneric, "db-sg"), nil)
+	}
+}
+
+// createSecret creates the application secret encrypted with the secrets key
+func (s *SecurityConstruct) createSecret(props *SecurityConstructProps) {
+	s.Secret = awssecretsmanager.NewSecret(s.Construct, jsii.String("ProdAppSecrets"), &awssecretsmanager.SecretProps{
+		SecretName:    jsii.String(props.Namer.Prefix() + "/app-secrets"),
+		Description:   jsii.String("Application secrets for production environment"),
+		EncryptionKey: s.KmsKeys[KeyDomainSecrets],
+		GenerateSecretString: &awssecretsmanager.SecretStringGenerator{
+			SecretStringTemplate: jsii.String(`{"username": "admin"}`),
+			GenerateStringKey:    jsii.String("password"),
+			ExcludeCharacters:    jsii.String(`"@/\`),
+		},
+		RemovalPolicy: props.RemovalPolicy,
+	})
+
+	awscdk.Tags_Of(s.Secret).Add(jsii.String("Name"), resourceName(props.Namer, ResourceGeneric, "app-secrets"), nil)
+}
+
+// createSSMParameters creates Systems Manager parameters for configuration
+func (s *SecurityConstruct) createSSMParameters(props *SecurityConstructProps) {
+	parameters := map[string]string{
+		"app-environment": *props.EnvironmentSuffix,
+		"app-version":     "1.0.0",
+		"log-level":       "INFO",
+		"max-connections": "100",
+	}
+
+	for key, value := range parameters {
+		param := awsssm.NewStringParameter(s.Construct, jsii.String("SSMParam"+key), &awsssm.StringParameterProps{
+			ParameterName: jsii.String(fmt.Sprintf("/%s/%s", props.Namer.Prefix(), key)),
+			StringValue:   jsii.String(value),
+			Description:   jsii.String(fmt.Sprintf("Configuration parameter for %s", key)),
+		})
+		s.SSMParameters[key] = param
+	}
+}
+
+// createSecretRotation enables automatic rotation of the application secret
+// with a custom rotation Lambda that regenerates the password in place. When
+// the data tier exists the secret holds the database master user and is
+// rotated by addDatabaseRotation instead.
+func (s *SecurityConstruct) createSecretRotation(props *SecurityConstructProps) {
+	if props.DatabaseEngine != DatabaseEngineNone {
+		return
+	}
+
+	subnets := &awsec2.SubnetSelection{
+		Subnets: props.PrivateSubnets,
+	}
+	securityGroups := &[]awsec2.ISecurityGroup{
+		s.SecurityGroups["lambda"],
+	}
+	s.Secret.AddRotationSchedule(jsii.String("ProdAppSecretsRotation"), &awssecretsmanager.RotationScheduleOptions{
+		AutomaticallyAfter: awscdk.Duration_Days(props.SecretRotationDays),
+		RotationLambda:     s.createRotationLambda(props, subnets, securityGroups),
+	})
+}
+
+// addDatabaseRotation rotates the master user of database with the hosted
+// single-user rotation Lambda. The schedule is added to the secret attached
+// to the cluster, so rotation only starts once host, port and engine are in
+// the secret. Multi-user rotation is not offered: it alternates between two
+// application users that must already exist in the database, and the stack
+// does not create database users.
+func (s *SecurityConstruct) addDatabaseRotation(props *SecurityConstructProps, database awsrds.DatabaseCluster) {
+	options := &awssecretsmanager.SingleUserHostedRotationOptions{
+		FunctionName: resourceName(props.Namer, ResourceLambdaFunction, "secret-rotation"),
+		Vpc:          props.Vpc,
+		VpcSubnets: &awsec2.SubnetSelection{
+			Subnets: props.PrivateSubnets,
+		},
+		SecurityGroups: &[]awsec2.ISecurityGroup{
+			s.SecurityGroups["lambda"],
+		},
+		ExcludeCharacters: jsii.String(`"@/\`),
+	}
+
+	var rotation awssecretsmanager.HostedRotation
+	switch props.DatabaseEngine {
+	case DatabaseEngineAuroraPostgres:
+		rotation = awssecretsmanager.HostedRotation_PostgreSqlSingleUser(options)
+	case DatabaseEngineAuroraMysql:
+		rotation = awssecretsmanager.HostedRotation_MysqlSingleUser(options)
+	default:
+		panic(fmt.Sprintf("unsupported database engine %q", props.DatabaseEngine))
+	}
+
+	database.Secret().AddRotationSchedule(jsii.String("ProdAppSecretsRotation"), &awssecretsmanager.RotationScheduleOptions{
+		AutomaticallyAfter: awscdk.Duration_Days(props.SecretRotationDays),
+		HostedRotation:     rotation,
+	})
+}
+
+// createRotationLambda creates a custom rotation Lambda for the application secret
+func (s *SecurityConstruct) createRotationLambda(props *SecurityConstructProps, subnets *awsec2.SubnetSelection, securityGroups *[]awsec2.ISecurityGroup) awslambda.Function {
+	rotationRole := awsiam.NewRole(s.Construct, jsii.String("ProdSecretRotationRole"), &awsiam.RoleProps{
+		RoleName:  resourceName(props.Namer, ResourceIamRole, "secret-rotation-role"),
+		AssumedBy: awsiam.NewServicePrincipal(jsii.String("lambda.amazonaws.com"), nil),
+		ManagedPolicies: &[]awsiam.IManagedPolicy{
+			awsiam.ManagedPolicy_FromAwsManagedPolicyName(jsii.String("service-role/AWSLambdaVPCAccessExecutionRole")),
+		},
+	})
+
+	logGroup := awslogs.NewLogGroup(s.Construct, jsii.String("ProdSecretRotationLogGroup"), &awslogs.LogGroupProps{
+		LogGroupName:  jsii.String("/aws/lambda/" + props.Namer.Name(ResourceLambdaFunction, "secret-rotation")),
+		EncryptionKey: s.KmsKeys[KeyDomainLogs],
+		Retention:     props.LogRetention,
+		RemovalPolicy: props.RemovalPolicy,
+	})
+
+	// Rotation handler implementing the four Secrets Manager rotation steps
+	rotationCode := `
+import json
+import logging
+import boto3
+
+logger = logging.getLogger()
+logger.setLevel(logging.INFO)
+
+client = boto3.client('secretsmanager')
+
+def lambda_handler(event, context):
+    """
+    Rotates the application secret by regenerating its password
+    """
+    arn = event['SecretId']
+    token = event['ClientRequestToken']
+    step = event['Step']
+
+    metadata = client.describe_secret(SecretId=arn)
+    if not metadata.get('RotationEnabled'):
+        raise ValueError(f"Secret {arn} is not enabled for rotation")
+
+    versions = metadata['VersionIdsToStages']
+    if token not in versions:
+        raise ValueError(f"Secret version {token} has no stage for rotation of secret {arn}")
+    if 'AWSCURRENT' in versions[token]:
+        logger.info(f"Secret version {token} already set as AWSCURRENT for secret {arn}")
+        return
+    if 'AWSPENDING' not in versions[token]:
+        raise ValueError(f"Secret version {token} not set as AWSPENDING for rotation of secret {arn}")
+
+    if step == 'createSecret':
+        create_secret(arn, token)
+    elif step in ('setSecret', 'testSecret'):
+        # The application reads the secret on demand - nothing to update downstream
+        logger.info(f"{step}: no downstream service to update for secret {arn}")
+    elif step == 'finishSecret':
+        finish_secret(arn, token, versions)
+    else:
+        raise ValueError(f"Invalid step parameter {step}")
+
+def create_secret(arn, token):
+    try:
+        client.get_secret_value(SecretId=arn, VersionId=token, VersionStage='AWSPENDING')
+        logger.info(f"createSecret: AWSPENDING already exists for secret {arn}")
+        return
+    except client.exceptions.ResourceNotFoundException:
+        pass
+
+    current = json.loads(client.get_secret_value(SecretId=arn, VersionStage='AWSCURRENT')['SecretString'])
+    current['password'] = client.get_random_password(ExcludeCharacters='"@/\\')['RandomPassword']
+    client.put_secret_value(
+        SecretId=arn,
+        ClientRequestToken=token,
+        SecretString=json.dumps(current),
+        VersionStages=['AWSPENDING'],
+    )
+    logger.info(f"createSecret: stored new AWSPENDING version {token} for secret {arn}")
+
+def finish_secret(arn, token, versions):
+    current_version = next((v for v, stages in versions.items() if 'AWSCURRENT' in stages), None)
+    client.update_secret_version_stage(
+        SecretId=arn,
+        VersionStage='AWSCURRENT',
+        MoveToVersionId=token,
+        RemoveFromVersionId=current_version,
+    )
+    logger.info(f"finishSecret: set AWSCURRENT to version {token} for secret {arn}")
+`
+
+	rotationFunction := awslambda.NewFunction(s.Construct, jsii.String("ProdSecretRotationFunction"), &awslambda.FunctionProps{
+		FunctionName:   resourceName(props.Namer, ResourceLambdaFunction, "secret-rotation"),
+		Runtime:        awslambda.Runtime_PYTHON_3_9(),
+		Code:           awslambda.Code_FromInline(jsii.String(rotationCode)),
+		Handler:        jsii.String("index.lambda_handler"),
+		MemorySize:     jsii.Number(128),
+		Timeout:        awscdk.Duration_Seconds(jsii.Number(30)),
+		Role:           rotationRole,
+		LogGroup:       logGroup,
+		Vpc:            props.Vpc,
+		VpcSubnets:     subnets,
+		SecurityGroups: securityGroups,
+		Description:    jsii.String("Rotates the application secret"),
+		Tracing:        awslambda.Tracing_ACTIVE,
+		Architecture:   awslambda.Architecture_X86_64(),
+	})
+
+	awscdk.Tags_Of(rotationFunction).Add(jsii.String("Name"), resourceName(props.Namer, ResourceGeneric, "secret-rotation"), nil)
+	awscdk.Tags_Of(rotationRole).Add(jsii.String("Name"), resourceName(props.Namer, ResourceGeneric, "secret-rotation-role"), nil)
+
+	return rotationFunction
+}
@@ -0,0 +1,246 @@
+package lib
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsec2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsrds"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awss3"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awssecretsmanager"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// StorageConstructProps defines the inputs of the StorageConstruct.
+type StorageConstructProps struct {
+	// Namer builds resource names.
+	Namer Namer
+	// KmsKeys encrypts the application bucket and database with the app data
+	// key and the logging bucket with the logs key.
+	KmsKeys       KmsKeys
+	RemovalPolicy awscdk.RemovalPolicy
+	// DatabaseEngine enables the Aurora cluster. The remaining database
+	// fields are only required when it is not DatabaseEngineNone.
+	DatabaseEngine        DatabaseEngine
+	Vpc                   awsec2.IVpc
+	IsolatedSubnets       *[]awsec2.ISubnet
+	DatabaseSecurityGroup awsec2.ISecurityGroup
+	// DatabaseCredentials is the secret holding the master username and password.
+	DatabaseCredentials awssecretsmanager.ISecret
+}
+
+// StorageConstruct holds the application and logging buckets and the optional
+// Aurora data tier.
+type StorageConstruct struct {
+	constructs.Construct
+	Bucket        awss3.Bucket
+	LoggingBucket awss3.Bucket
+	Database      awsrds.DatabaseCluster
+}
+
+// NewStorageConstruct creates the encrypted buckets and database.
+func NewStorageConstruct(scope constructs.Construct, id *string, props *StorageConstructProps) *StorageConstruct {
+	storage := &StorageConstruct{
+		Construct: constructs.NewConstruct(scope, id),
+	}
+
+	storage.createBuckets(props)
+	storage.createDatabase(props)
+
+	return storage
+}
+
+// autoDeleteObjects empties buckets on stack deletion only when they are destroyed with it
+func autoDeleteObjects(removalPolicy awscdk.RemovalPolicy) *bool {
+	return jsii.Bool(removalPolicy == awscdk.RemovalPolicy_DESTROY)
+}
+
+// allowLogDelivery lets the log delivery service, used by Resolver query
+// logging and Network Firewall, write under prefix of the KMS-encrypted
+// logging bucket. The key policy already lets it generate data keys.
+func allowLogDelivery(bucket awss3.IBucket, prefix string) {
+	account := awscdk.Stack_Of(bucket).Account()
+	delivery := awsiam.NewServicePrincipal(jsii.String("delivery.logs.amazonaws.com"), nil)
+	sourceAccount := &map[string]interface{}{
+		"StringEquals": map[string]interface{}{"aws:SourceAccount": account},
+	}
+
+	bucket.AddToResourcePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Effect:     awsiam.Effect_ALLOW,
+		Principals: &[]awsiam.IPrincipal{delivery},
+		Actions: &[]*string{
+			jsii.String("s3:PutObject"),
+		},
+		Resources: &[]*string{
+			jsii.String(*bucket.BucketArn() + "/" + prefix + "/AWSLogs/" + *account + "/*"),
+		},
+		Conditions: &map[string]interface{}{
+			"StringEquals": map[string]interface{}{
+				"s3:x-amz-acl":      "bucket-owner-full-control",
+				"aws:SourceAccount": account,
+			},
+		},
+	}))
+	bucket.AddToResourcePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Effect:     awsiam.Effect_ALLOW,
+		Principals: &[]awsiam.IPrincipal{delivery},
+		Actions: &[]*string{
+			jsii.String("s3:GetBucketAcl"),
+		},
+		Resources: &[]*string{
+			bucket.BucketArn(),
+		},
+		Conditions: sourceAccount,
+	}))
+}
+
+// createBuckets creates S3 buckets with customer-managed KMS encryption
+func (s *StorageConstruct) createBuckets(props *StorageConstructProps) {
+	// Main application S3 bucket
+	s.Bucket = awss3.NewBucket(s.Construct, jsii.String("ProdS3Bucket"), &awss3.BucketProps{
+		BucketName:        bucketName(s.Construct, props.Namer, "app-bucket"),
+		Versioned:         jsii.Bool(true),
+		RemovalPolicy:     props.RemovalPolicy,
+		AutoDeleteObjects: autoDeleteObjects(props.RemovalPolicy),
+		BlockPublicAccess: awss3.BlockPublicAccess_BLOCK_ALL(),
+		EncryptionKey:     props.KmsKeys[KeyDomainAppData],
+		Encryption:        awss3.BucketEncryption_KMS,
+	})
+
+	// Separate logging bucket for security events - CloudFront requires ACL access
+	s.LoggingBucket = awss3.NewBucket(s.Construct, jsii.String("ProdLoggingBucket"), &awss3.BucketProps{
+		BucketName:        bucketName(s.Construct, props.Namer, "logging-bucket"),
+		Versioned:         jsii.Bool(true),
+		RemovalPolicy:     props.RemovalPolicy,
+		AutoDeleteObjects: autoDeleteObjects(props.RemovalPolicy),
+		BlockPublicAccess: awss3.NewBlockPublicAccess(&awss3.BlockPublicAccessOptions{
+			BlockPublicAcls:       jsii.Bool(true),
+			BlockPublicPolicy:     jsii.Bool(true),
+			IgnorePublicAcls:      jsii.Bool(true),
+			RestrictPublicBuckets: jsii.Bool(false), // Allow CloudFront service to write logs
+		}),
+		ObjectOwnership: awss3.ObjectOwnership_BUCKET_OWNER_PREFERRED,
+		EncryptionKey:   props.KmsKeys[KeyDomainLogs],
+		Encryption:      awss3.BucketEncryption_KMS,
+	})
+
+	// Add bucket policy to allow CloudTrail service to write logs
+	// Separate statement for PutObject with ACL condition
+	s.LoggingBucket.AddToResourcePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Effect: awsiam.Effect_ALLOW,
+		Principals: &[]awsiam.IPrincipal{
+			awsiam.NewServicePrincipal(jsii.String("cloudtrail.amazonaws.com"), nil),
+		},
+		Actions: &[]*string{
+			jsii.String("s3:PutObject"),
+		},
+		Resources: &[]*string{
+			jsii.String(*s.LoggingBucket.BucketArn() + "/*"),
+		},
+		Conditions: &map[string]interface{}{
+			"StringEquals": map[string]interface{}{
+				"s3:x-amz-acl": "bucket-owner-full-control",
+			},
+		},
+	}))
+
+	// Separate statement for GetBucketAcl without conditions
+	s.LoggingBucket.AddToResourcePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Effect: awsiam.Effect_ALLOW,
+		Principals: &[]awsiam.IPrincipal{
+			awsiam.NewServicePrincipal(jsii.String("cloudtrail.amazonaws.com"), nil),
+		},
+		Actions: &[]*string{
+			jsii.String("s3:GetBucketAcl"),
+		},
+		Resources: &[]*string{
+			s.LoggingBucket.BucketArn(),
+		},
+	}))
+
+	// Also allow CloudTrail to check bucket location and encryption
+	s.LoggingBucket.AddToResourcePolicy(awsiam.NewPolicyStatement(&awsiam.PolicyStatementProps{
+		Effect: awsiam.Effect_ALLOW,
+		Principals: &[]awsiam.IPrincipal{
+			awsiam.NewServicePrincipal(jsii.String("cloudtrail.amazonaws.com"), nil),
+		},
+		Actions: &[]*string{
+			jsii.String("s3:GetBucketLocation"),
+			jsii.String("s3:GetBucketVersioning"),
+		},
+		Resources: &[]*string{
+			s.LoggingBucket.BucketArn(),
+		},
+	}))
+
+	awscdk.Tags_Of(s.Bucket).Add(jsii.String("Name"), resourceName(props.Namer, ResourceGeneric, "app-bucket"), nil)
+	awscdk.Tags_Of(s.LoggingBucket).Add(jsii.String("Name"), resourceName(props.Namer, ResourceGeneric, "logging-bucket"), nil)
+}
+
+// createDatabase creates an encrypted Aurora cluster in the isolated subnets
+func (s *StorageConstruct) createDatabase(props *StorageConstructProps) {
+	if props.DatabaseEngine == DatabaseEngineNone {
+		return
+	}
+
+	var engine awsrds.IClusterEngine
+	var logExports []*string
+	switch props.DatabaseEngine {
+	case DatabaseEngineAuroraMysql:
+		engine = awsrds.DatabaseClusterEngine_AuroraMysql(&awsrds.AuroraMysqlClusterEngineProps{
+			Version: awsrds.AuroraMysqlEngineVersion_VER_3_04_0(),
+		})
+		logExports = []*string{jsii.String("error"), jsii.String("slowquery")}
+	case DatabaseEngineAuroraPostgres:
+		engine = awsrds.DatabaseClusterEngine_AuroraPostgres(&awsrds.AuroraPostgresClusterEngineProps{
+			Version: awsrds.AuroraPostgresEngineVersion_VER_15_4(),
+		})
+		logExports = []*string{jsii.String("postgresql")}
+	default:
+		panic(fmt.Sprintf("unsupported database engine %q", props.DatabaseEngine))
+	}
+
+	subnetGroup := awsrds.NewSubnetGroup(s.Construct, jsii.String("ProdDBSubnetGroup"), &awsrds.SubnetGroupProps{
+		SubnetGroupName: resourceName(props.Namer, ResourceGeneric, "db-subnets"),
+		Description:     jsii.String(fmt.Sprintf("Isolated subnets for %s database", props.Namer.Prefix())),
+		Vpc:             props.Vpc,
+		VpcSubnets: &awsec2.SubnetSelection{
+			Subnets: props.IsolatedSubnets,
+		},
+		RemovalPolicy: props.RemovalPolicy,
+	})
+
+	instanceType := awsec2.InstanceType_Of(awsec2.InstanceClass_T4G, awsec2.InstanceSize_MEDIUM)
+	s.Database = awsrds.NewDatabaseCluster(s.Construct, jsii.String("ProdDatabase"), &awsrds.DatabaseClusterProps{
+		ClusterIdentifier: resourceName(props.Namer, ResourceDatabaseCluster, "db"),
+		Engine:            engine,
+		// Master credentials come from the generated application secret
+		Credentials: awsrds.Credentials_FromSecret(props.DatabaseCredentials, nil),
+		Writer: awsrds.ClusterInstance_Provisioned(jsii.String("Writer"), &awsrds.ProvisionedClusterInstanceProps{
+			InstanceType: instanceType,
+		}),
+		Readers: &[]awsrds.IClusterInstance{
+			awsrds.ClusterInstance_Provisioned(jsii.String("Reader"), &awsrds.ProvisionedClusterInstanceProps{
+				InstanceType: instanceType,
+			}),
+		},
+		DefaultDatabaseName:   jsii.String("appdb"),
+		Port:                  jsii.Number(props.DatabaseEngine.Port()),
+		Vpc:                   props.Vpc,
+		SubnetGroup:           subnetGroup,
+		SecurityGroups:        &[]awsec2.ISecurityGroup{props.DatabaseSecurityGroup},
+		StorageEncrypted:      jsii.Bool(true),
+		StorageEncryptionKey:  props.KmsKeys[KeyDomainAppData],
+		IamAuthentication:     jsii.Bool(true),
+		CloudwatchLogsExports: &logExports,
+		Backup: &awsrds.BackupProps{
+			Retention: awscdk.Duration_Days(jsii.Number(7)),
+		},
+		CopyTagsToSnapshot: jsii.Bool(true),
+		RemovalPolicy:      props.RemovalPolicy,
+	})
+
+	awscdk.Tags_Of(s.Database).Add(jsii.String("Name"), resourceName(props.Namer, ResourceGeneric, "db"), nil)
+}
@@ -0,0 +1,109 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsec2"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// maxTransitGatewayCidrs bounds DestinationCidrs; every CIDR takes a network
+// ACL entry in the app and data tiers, which allow 20 per direction.
+const maxTransitGatewayCidrs = 10
+
+var transitGatewayIdPattern = regexp.MustCompile(`^tgw-[0-9a-f]{8,17}$`)
+
+// TransitGatewayConfig attaches the VPC to a shared Transit Gateway.
+type TransitGatewayConfig struct {
+	// TransitGatewayId is the shared Transit Gateway, e.g. tgw-0123456789abcdef0.
+	TransitGatewayId *string
+	// DestinationCidrs are routed from the private and isolated tiers to the
+	// Transit Gateway, and admitted by their network ACLs.
+	DestinationCidrs []string
+	// ReservedCidrs are address ranges already used elsewhere in the network,
+	// e.g. other spokes or on-premises. The VPC CIDR must not overlap them.
+	ReservedCidrs []string
+}
+
+// Validate checks the attachment settings against the VPC CIDR.
+func (c *TransitGatewayConfig) Validate(vpcCidr *string) error {
+	var errs []error
+	if c.TransitGatewayId == nil {
+		errs = append(errs, errors.New("TransitGateway requires TransitGatewayId"))
+	} else if isConcrete(c.TransitGatewayId) && !transitGatewayIdPattern.MatchString(*c.TransitGatewayId) {
+		errs = append(errs, fmt.Errorf("TransitGatewayId %q is not a Transit Gateway ID", *c.TransitGatewayId))
+	}
+	if len(c.DestinationCidrs) == 0 || len(c.DestinationCidrs) > maxTransitGatewayCidrs {
+		errs = append(errs, fmt.Errorf("TransitGateway requires between 1 and %d DestinationCidrs", maxTransitGatewayCidrs))
+	}
+
+	var vpcNet *net.IPNet
+	if vpcCidr != nil {
+		_, vpcNet, _ = net.ParseCIDR(*vpcCidr)
+	}
+	for _, ranges := range []struct {
+		field string
+		cidrs []string
+	}{
+		{"DestinationCidrs", c.DestinationCidrs},
+		{"ReservedCidrs", c.ReservedCidrs},
+	} {
+		for _, cidr := range ranges.cidrs {
+			ip, ipNet, err := net.ParseCIDR(cidr)
+			if err != nil || ip.To4() == nil {
+				errs = append(errs, fmt.Errorf("TransitGateway %s entry %q is not a valid IPv4 CIDR block", ranges.field, cidr))
+			} else if vpcNet != nil && cidrsOverlap(vpcNet, ipNet) {
+				errs = append(errs, fmt.Errorf("VpcCidr %s overlaps TransitGateway %s entry %s", *vpcCidr, ranges.field, cidr))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// cidrsOverlap reports whether two CIDR blocks share any address
+func cidrsOverlap(a, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
+
+// attachTransitGateway attaches the VPC to the Transit Gateway from its own
+// subnets and routes the destination CIDRs to it from the private and
+// isolated tiers
+func (n *NetworkConstruct) attachTransitGateway(props *NetworkConstructProps) {
+	transit := props.TransitGateway
+
+	subnetIds := make([]*string, 0, len(*n.TransitSubnets))
+	for _, subnet := range *n.TransitSubnets {
+		subnetIds = append(subnetIds, subnet.SubnetId())
+	}
+	n.TransitGatewayAttachment = awsec2.NewCfnTransitGatewayAttachment(n.Construct, jsii.String("TransitGatewayAttachment"), &awsec2.CfnTransitGatewayAttachmentProps{
+		TransitGatewayId: transit.TransitGatewayId,
+		VpcId:            n.Vpc.VpcId(),
+		SubnetIds:        &subnetIds,
+	})
+	awscdk.Tags_Of(n.TransitGatewayAttachment).Add(jsii.String("Name"), resourceName(props.Namer, ResourceGeneric, "tgw-attachment"), nil)
+
+	tiers := []struct {
+		id      string
+		subnets *[]awsec2.ISubnet
+	}{
+		{"Private", n.PrivateSubnets},
+		{"Isolated", n.IsolatedSubnets},
+	}
+	for _, tier := range tiers {
+		for i, subnet := range *tier.subnets {
+			for j, cidr := range transit.DestinationCidrs {
+				route := awsec2.NewCfnRoute(n.Construct, jsii.String(fmt.Sprintf("%sSubnet%dTransitRoute%d", tier.id, i+1, j+1)), &awsec2.CfnRouteProps{
+					RouteTableId:         subnet.RouteTable().RouteTableId(),
+					DestinationCidrBlock: jsii.String(cidr),
+					TransitGatewayId:     transit.TransitGatewayId,
+				})
+				// The Transit Gateway only accepts routes once the VPC is attached
+				route.AddDependency(n.TransitGatewayAttachment)
+			}
+		}
+	}
+}
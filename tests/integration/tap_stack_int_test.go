@@ -65,6 +65,7 @@ func TestTapStackIntegration(t *testing.T) {
 		assert.NotNil(t, stack.LoggingBucket, "Logging Bucket should be created")
 		assert.NotNil(t, stack.LambdaFunction, "Lambda Function should be created")
 		assert.NotNil(t, stack.AutoScalingGroup, "Auto Scaling Group should be created")
+		assert.NotNil(t, stack.LoadBalancer, "Application Load Balancer should be created")
 		assert.NotNil(t, stack.BastionHost, "Bastion Host should be created")
 		assert.NotNil(t, stack.CloudFrontDist, "CloudFront Distribution should be created")
 		assert.NotNil(t, stack.WAF, "WAF should be created")
@@ -76,6 +77,7 @@ func TestTapStackIntegration(t *testing.T) {
 		// Verify security groups
 		assert.Contains(t, stack.SecurityGroups, "lambda", "Lambda security group should exist")
 		assert.Contains(t, stack.SecurityGroups, "ec2", "EC2 security group should exist")
+		assert.Contains(t, stack.SecurityGroups, "alb", "ALB security group should exist")
 		assert.Contains(t, stack.SecurityGroups, "bastion", "Bastion security group should exist")
 
 		// Verify SSM parameters
@@ -124,7 +126,7 @@ func TestTapStackIntegration(t *testing.T) {
 		assert.NotNil(t, stack.LoggingBucket)
 
 		// Verify security groups exist with proper configuration
-		assert.Len(t, stack.SecurityGroups, 3, "Should have 3 security groups")
+		assert.Len(t, stack.SecurityGroups, 4, "Should have 4 security groups")
 
 		// Verify Lambda function has security configuration
 		assert.NotNil(t, stack.LambdaFunction)
@@ -0,0 +1,169 @@
+package lib_test
+
+import (
+	"testing"
+
+	"github.com/TuringGpt/iac-test-automations/lib"
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/assertions"
+	"github.com/aws/jsii-runtime-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// synthBastionMode synthesizes a TapStack with the given compute configuration
+func synthBastionMode(t *testing.T, id string, compute *lib.ComputeConfig) (*lib.TapStack, assertions.Template) {
+	t.Helper()
+	app := awscdk.NewApp(nil)
+	stack := lib.NewTapStack(app, jsii.String(id), &lib.TapStackProps{
+		EnvironmentSuffix: jsii.String("bastion"),
+		Compute:           compute,
+	})
+	return stack, assertions.Template_FromStack(stack.Stack, nil)
+}
+
+func TestBastionMode(t *testing.T) {
+	defer jsii.Close()
+
+	t.Run("ssm is the default and opens no SSH", func(t *testing.T) {
+		// ARRANGE
+		stack, template := synthBastionMode(t, "TapStackBastionSsm", nil)
+
+		// ASSERT - private subnet, no inbound rules anywhere for port 22
+		assert.Equal(t, lib.BastionModeSsm, stack.Config.Compute.BastionMode)
+		require.NotNil(t, stack.BastionHost)
+		privateSubnet := (*stack.PrivateSubnets)[0].Node().DefaultChild().(awscdk.CfnElement)
+		template.HasResourceProperties(jsii.String("AWS::EC2::Instance"), map[string]interface{}{
+			"SubnetId": map[string]interface{}{"Ref": logicalId(stack, privateSubnet)},
+		})
+		template.HasResourceProperties(jsii.String("AWS::EC2::SecurityGroup"), map[string]interface{}{
+			"GroupDescription":     "Security group for the Session Manager bastion host (no inbound access)",
+			"SecurityGroupIngress": assertions.Match_Absent(),
+		})
+		sshRules := template.FindResources(jsii.String("AWS::EC2::SecurityGroupIngress"), map[string]interface{}{
+			"Properties": map[string]interface{}{"FromPort": 22},
+		})
+		assert.Empty(t, *sshRules)
+		for id, resource := range *template.FindResources(jsii.String("AWS::EC2::SecurityGroup"), nil) {
+			ingress, _ := (*resource)["Properties"].(map[string]interface{})["SecurityGroupIngress"].([]interface{})
+			for _, rule := range ingress {
+				assert.NotEqualf(t, float64(22), rule.(map[string]interface{})["FromPort"], "%s allows SSH", id)
+			}
+		}
+	})
+
+	t.Run("ssm logs sessions encrypted with the stack key", func(t *testing.T) {
+		// ARRANGE
+		stack, template := synthBastionMode(t, "TapStackBastionSessionLogs", nil)
+		keyLogicalId := logicalId(stack, stack.KmsKey.Node().DefaultChild().(awscdk.CfnElement))
+
+		// ASSERT
+		require.NotNil(t, stack.Compute.SessionPreferences)
+		template.HasResourceProperties(jsii.String("AWS::SSM::Document"), map[string]interface{}{
+			"DocumentType": "Session",
+			"Content": assertions.Match_ObjectLike(&map[string]interface{}{
+				"sessionType": "Standard_Stream",
+				"inputs": assertions.Match_ObjectLike(&map[string]interface{}{
+					"kmsKeyId":                    map[string]interface{}{"Ref": keyLogicalId},
+					"s3EncryptionEnabled":         true,
+					"cloudWatchEncryptionEnabled": true,
+					"s3BucketName":                map[string]interface{}{"Ref": logicalId(stack, stack.LoggingBucket.Node().DefaultChild().(awscdk.CfnElement))},
+				}),
+			}),
+		})
+		template.HasResourceProperties(jsii.String("AWS::Logs::LogGroup"), map[string]interface{}{
+			"LogGroupName": "/aws/ssm/prod-bastion-sessions",
+			"KmsKeyId":     map[string]interface{}{"Fn::GetAtt": []interface{}{keyLogicalId, "Arn"}},
+		})
+		template.HasOutput(jsii.String("SessionPreferencesDocument"), map[string]interface{}{})
+	})
+
+	t.Run("cidr-allowlist limits SSH to the configured CIDRs", func(t *testing.T) {
+		// ARRANGE
+		stack, template := synthBastionMode(t, "TapStackBastionAllowlist", &lib.ComputeConfig{
+			BastionMode:         lib.BastionModeCidrAllowlist,
+			BastionAllowedCidrs: []string{"203.0.113.0/24", "2001:db8::/32"},
+		})
+
+		// ASSERT
+		publicSubnet := (*stack.PublicSubnets)[0].Node().DefaultChild().(awscdk.CfnElement)
+		template.HasResourceProperties(jsii.String("AWS::EC2::Instance"), map[string]interface{}{
+			"SubnetId": map[string]interface{}{"Ref": logicalId(stack, publicSubnet)},
+		})
+		template.HasResourceProperties(jsii.String("AWS::EC2::SecurityGroup"), map[string]interface{}{
+			"GroupDescription": "Security group for bastion host SSH access",
+			"SecurityGroupIngress": []interface{}{
+				assertions.Match_ObjectLike(&map[string]interface{}{"CidrIp": "203.0.113.0/24", "FromPort": 22, "ToPort": 22}),
+				assertions.Match_ObjectLike(&map[string]interface{}{"CidrIpv6": "2001:db8::/32", "FromPort": 22, "ToPort": 22}),
+			},
+		})
+		template.HasResourceProperties(jsii.String("AWS::EC2::SecurityGroup"), map[string]interface{}{
+			"GroupDescription": "Security group for EC2 instances in private subnets",
+			"SecurityGroupIngress": assertions.Match_ArrayWith(&[]interface{}{
+				assertions.Match_ObjectLike(&map[string]interface{}{
+					"Description":           "SSH from bastion host",
+					"FromPort":              22,
+					"SourceSecurityGroupId": assertions.Match_AnyValue(),
+				}),
+			}),
+		})
+		template.ResourceCountIs(jsii.String("AWS::SSM::Document"), jsii.Number(0))
+		assert.Nil(t, stack.Compute.SessionPreferences)
+	})
+
+	t.Run("none creates no bastion", func(t *testing.T) {
+		// ARRANGE
+		stack, template := synthBastionMode(t, "TapStackBastionNone", &lib.ComputeConfig{BastionMode: lib.BastionModeNone})
+
+		// ASSERT
+		assert.Nil(t, stack.BastionHost)
+		assert.NotContains(t, stack.SecurityGroups, "bastion")
+		template.ResourceCountIs(jsii.String("AWS::EC2::Instance"), jsii.Number(0))
+		template.ResourceCountIs(jsii.String("AWS::SSM::Document"), jsii.Number(0))
+		assert.Empty(t, *template.FindOutputs(jsii.String("BastionHostId"), nil))
+	})
+
+	t.Run("rejects inconsistent bastion settings", func(t *testing.T) {
+		cases := []struct {
+			name    string
+			compute *lib.ComputeConfig
+			message string
+		}{
+			{
+				name:    "unknown mode",
+				compute: &lib.ComputeConfig{BastionMode: "public"},
+				message: `unsupported BastionMode "public"`,
+			},
+			{
+				name:    "allowlist without CIDRs",
+				compute: &lib.ComputeConfig{BastionMode: lib.BastionModeCidrAllowlist},
+				message: "requires at least one BastionAllowedCidrs entry",
+			},
+			{
+				name:    "allowlist open to the internet",
+				compute: &lib.ComputeConfig{BastionMode: lib.BastionModeCidrAllowlist, BastionAllowedCidrs: []string{"0.0.0.0/0"}},
+				message: "would open SSH to the internet",
+			},
+			{
+				name:    "invalid CIDR",
+				compute: &lib.ComputeConfig{BastionMode: lib.BastionModeCidrAllowlist, BastionAllowedCidrs: []string{"203.0.113.0"}},
+				message: "is not a valid CIDR block",
+			},
+			{
+				name:    "CIDRs outside allowlist mode",
+				compute: &lib.ComputeConfig{BastionAllowedCidrs: []string{"203.0.113.0/24"}},
+				message: "BastionAllowedCidrs only applies to BastionMode cidr-allowlist",
+			},
+		}
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				// ACT
+				err := (&lib.TapStackProps{Compute: tc.compute}).Validate()
+
+				// ASSERT
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.message)
+			})
+		}
+	})
+}
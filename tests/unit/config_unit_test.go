@@ -0,0 +1,200 @@
+package lib_test
+
+import (
+	"testing"
+
+	"github.com/TuringGpt/iac-test-automations/lib"
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/assertions"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awscloudfront"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslogs"
+	"github.com/aws/jsii-runtime-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStackConfig(t *testing.T) {
+	defer jsii.Close()
+
+	t.Run("applies documented defaults when props are empty", func(t *testing.T) {
+		// ACT
+		config, err := (&lib.TapStackProps{}).Config()
+
+		// ASSERT
+		require.NoError(t, err)
+		assert.Equal(t, "10.0.0.0/16", *config.Network.VpcCidr)
+		assert.Equal(t, float64(2), *config.Network.MaxAzs)
+		assert.Equal(t, "t3.micro", *config.Compute.InstanceType)
+		assert.Equal(t, "t3.nano", *config.Compute.BastionInstanceType)
+		assert.Equal(t, float64(256), *config.Lambda.MemorySize)
+		assert.Equal(t, awscloudfront.PriceClass_PRICE_CLASS_100, config.Cdn.PriceClass)
+		assert.Equal(t, awslogs.RetentionDays_ONE_MONTH, config.Logging.Retention)
+	})
+
+	t.Run("keeps defaults for fields left unset in a sub-struct", func(t *testing.T) {
+		// ACT
+		config, err := (&lib.TapStackProps{
+			Compute: &lib.ComputeConfig{InstanceType: jsii.String("m6i.large")},
+		}).Config()
+
+		// ASSERT
+		require.NoError(t, err)
+		assert.Equal(t, "m6i.large", *config.Compute.InstanceType)
+		assert.Equal(t, float64(3), *config.Compute.MaxCapacity)
+	})
+
+	tests := []struct {
+		name    string
+		props   lib.TapStackProps
+		wantErr string
+	}{
+		{
+			name: "desired capacity above max",
+			props: lib.TapStackProps{
+				Compute: &lib.ComputeConfig{DesiredCapacity: jsii.Number(5)},
+			},
+			wantErr: "DesiredCapacity 5 must be between MinCapacity 1 and MaxCapacity 3",
+		},
+		{
+			name: "min capacity above max",
+			props: lib.TapStackProps{
+				Compute: &lib.ComputeConfig{MinCapacity: jsii.Number(4), DesiredCapacity: jsii.Number(4)},
+			},
+			wantErr: "MinCapacity 4 exceeds MaxCapacity 3",
+		},
+		{
+			name: "CIDR too small for the AZ count",
+			props: lib.TapStackProps{
+				Network: &lib.NetworkConfig{VpcCidr: jsii.String("10.0.0.0/22"), MaxAzs: jsii.Number(3)},
+			},
+			wantErr: "fits 4 /24 subnets but 3 Availability Zones need 9",
+		},
+		{
+			name: "subnet mask shorter than the VPC prefix",
+			props: lib.TapStackProps{
+				Network: &lib.NetworkConfig{VpcCidr: jsii.String("10.0.0.0/24"), SubnetCidrMask: jsii.Number(20)},
+			},
+			wantErr: "must be longer than the VPC prefix",
+		},
+		{
+			name: "invalid CIDR",
+			props: lib.TapStackProps{
+				Network: &lib.NetworkConfig{VpcCidr: jsii.String("10.0.0.300/16")},
+			},
+			wantErr: "is not a valid IPv4 CIDR block",
+		},
+		{
+			name: "single AZ",
+			props: lib.TapStackProps{
+				Network: &lib.NetworkConfig{MaxAzs: jsii.Number(1)},
+			},
+			wantErr: "MaxAzs must be a whole number of at least 2",
+		},
+		{
+			name: "malformed instance type",
+			props: lib.TapStackProps{
+				Compute: &lib.ComputeConfig{InstanceType: jsii.String("large")},
+			},
+			wantErr: "InstanceType must look like",
+		},
+		{
+			name: "Lambda memory out of range",
+			props: lib.TapStackProps{
+				Lambda: &lib.LambdaConfig{MemorySize: jsii.Number(64)},
+			},
+			wantErr: "MemorySize must be a whole number of MB between 128 and 10240",
+		},
+		{
+			name: "unknown price class",
+			props: lib.TapStackProps{
+				Cdn: &lib.CdnConfig{PriceClass: "PRICE_CLASS_50"},
+			},
+			wantErr: `unsupported PriceClass "PRICE_CLASS_50"`,
+		},
+		{
+			name: "invalid blocked CIDR",
+			props: lib.TapStackProps{
+				Cdn: &lib.CdnConfig{BlockedCidrs: []string{"2001:db8::/129"}},
+			},
+			wantErr: `cdn: BlockedCidrs entry "2001:db8::/129" is not a valid CIDR block`,
+		},
+		{
+			name: "unknown log retention",
+			props: lib.TapStackProps{
+				Logging: &lib.LoggingConfig{Retention: "FOREVER"},
+			},
+			wantErr: `unsupported Retention "FOREVER"`,
+		},
+		{
+			name: "unknown database engine",
+			props: lib.TapStackProps{
+				DatabaseEngine: "postgres",
+			},
+			wantErr: `unsupported DatabaseEngine "postgres", must be one of "aurora-postgresql", "aurora-mysql", or empty for no database`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run("rejects "+tc.name, func(t *testing.T) {
+			err := tc.props.Validate()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.wantErr)
+		})
+	}
+
+	t.Run("panics on invalid props before synthesis", func(t *testing.T) {
+		app := awscdk.NewApp(nil)
+		assert.Panics(t, func() {
+			lib.NewTapStack(app, jsii.String("InvalidConfigTest"), &lib.TapStackProps{
+				StackProps: &awscdk.StackProps{},
+				Compute:    &lib.ComputeConfig{DesiredCapacity: jsii.Number(10)},
+			})
+		})
+	})
+
+	t.Run("synthesizes configured sizing", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		stack := lib.NewTapStack(app, jsii.String("ConfigTest"), &lib.TapStackProps{
+			StackProps:        &awscdk.StackProps{},
+			EnvironmentSuffix: jsii.String("config-test"),
+			Network:           &lib.NetworkConfig{VpcCidr: jsii.String("10.20.0.0/20"), SubnetCidrMask: jsii.Number(26)},
+			Compute:           &lib.ComputeConfig{InstanceType: jsii.String("t3.small"), MinCapacity: jsii.Number(2), MaxCapacity: jsii.Number(6), DesiredCapacity: jsii.Number(4)},
+			Lambda:            &lib.LambdaConfig{MemorySize: jsii.Number(512), TimeoutSeconds: jsii.Number(60)},
+			Cdn:               &lib.CdnConfig{PriceClass: awscloudfront.PriceClass_PRICE_CLASS_ALL},
+			Logging:           &lib.LoggingConfig{Retention: awslogs.RetentionDays_ONE_WEEK},
+		})
+		template := assertions.Template_FromStack(stack.Stack, nil)
+
+		// ASSERT
+		template.HasResourceProperties(jsii.String("AWS::EC2::VPC"), map[string]interface{}{
+			"CidrBlock": "10.20.0.0/20",
+		})
+		template.HasResourceProperties(jsii.String("AWS::EC2::Subnet"), map[string]interface{}{
+			"CidrBlock": "10.20.0.0/26",
+		})
+		template.HasResourceProperties(jsii.String("AWS::EC2::LaunchTemplate"), map[string]interface{}{
+			"LaunchTemplateData": map[string]interface{}{
+				"InstanceType": "t3.small",
+			},
+		})
+		template.HasResourceProperties(jsii.String("AWS::AutoScaling::AutoScalingGroup"), map[string]interface{}{
+			"MinSize":         "2",
+			"MaxSize":         "6",
+			"DesiredCapacity": "4",
+		})
+		template.HasResourceProperties(jsii.String("AWS::Lambda::Function"), map[string]interface{}{
+			"Handler":    "index.lambda_handler",
+			"MemorySize": 512,
+			"Timeout":    60,
+		})
+		template.HasResourceProperties(jsii.String("AWS::CloudFront::Distribution"), map[string]interface{}{
+			"DistributionConfig": map[string]interface{}{
+				"PriceClass": "PriceClass_All",
+			},
+		})
+		template.HasResourceProperties(jsii.String("AWS::Logs::LogGroup"), map[string]interface{}{
+			"RetentionInDays": 7,
+		})
+	})
+}
@@ -0,0 +1,80 @@
+package lib_test
+
+import (
+	"testing"
+
+	"github.com/TuringGpt/iac-test-automations/lib"
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/assertions"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awscloudfront"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awss3"
+	"github.com/aws/jsii-runtime-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConstructs(t *testing.T) {
+	defer jsii.Close()
+
+	t.Run("NetworkConstruct can be used on its own", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		stack := awscdk.NewStack(app, jsii.String("NetworkOnly"), nil)
+		network := lib.NewNetworkConstruct(stack, jsii.String("Network"), &lib.NetworkConstructProps{
+			Namer:   lib.NewDefaultNamer("reuse"),
+			Network: lib.DefaultStackConfig().Network,
+		})
+		template := assertions.Template_FromStack(stack, nil)
+
+		// ASSERT
+		template.ResourceCountIs(jsii.String("AWS::EC2::VPC"), jsii.Number(1))
+		template.ResourceCountIs(jsii.String("AWS::EC2::Subnet"), jsii.Number(6))
+		template.ResourceCountIs(jsii.String("AWS::S3::Bucket"), jsii.Number(0))
+		assert.Len(t, *network.PublicSubnets, 2)
+		assert.Len(t, *network.PrivateSubnets, 2)
+		assert.Len(t, *network.IsolatedSubnets, 2)
+	})
+
+	t.Run("EdgeConstruct fronts an imported bucket", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		stack := awscdk.NewStack(app, jsii.String("EdgeOnly"), &awscdk.StackProps{
+			Env: &awscdk.Environment{Account: jsii.String("123456789012"), Region: jsii.String("us-east-1")},
+		})
+		edge := lib.NewEdgeConstruct(stack, jsii.String("Edge"), &lib.EdgeConstructProps{
+			Namer:        lib.NewDefaultNamer("reuse"),
+			OriginBucket: awss3.Bucket_FromBucketName(stack, jsii.String("Origin"), jsii.String("existing-assets")),
+			LogBucket:    awss3.Bucket_FromBucketName(stack, jsii.String("Logs"), jsii.String("existing-logs")),
+			PriceClass:   awscloudfront.PriceClass_PRICE_CLASS_100,
+		})
+		template := assertions.Template_FromStack(stack, nil)
+
+		// ASSERT
+		template.ResourceCountIs(jsii.String("AWS::CloudFront::Distribution"), jsii.Number(1))
+		template.HasResourceProperties(jsii.String("AWS::WAFv2::WebACL"), map[string]interface{}{
+			"Name": "prod-reuse-waf",
+		})
+		template.ResourceCountIs(jsii.String("AWS::EC2::VPC"), jsii.Number(0))
+		assert.NotNil(t, edge.WAF)
+		assert.NotNil(t, edge.Distribution)
+	})
+
+	t.Run("TapStack exposes the constructs it is composed of", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		stack := lib.NewTapStack(app, jsii.String("TapStackComposition"), &lib.TapStackProps{
+			EnvironmentSuffix: jsii.String("compose"),
+			DatabaseEngine:    lib.DatabaseEngineAuroraPostgres,
+		})
+
+		// ASSERT - Stack-level fields are the construct outputs
+		assert.Equal(t, stack.Network.Vpc, stack.Vpc)
+		assert.Equal(t, stack.Security.KmsKey, stack.KmsKey)
+		assert.Equal(t, stack.Security.Secret, stack.SecretsManager)
+		assert.Equal(t, stack.Storage.Bucket, stack.S3Bucket)
+		assert.Equal(t, stack.Storage.Database, stack.Database)
+		assert.Equal(t, stack.Compute.LoadBalancer, stack.LoadBalancer)
+		assert.Equal(t, stack.Edge.Distribution, stack.CloudFrontDist)
+		assert.Equal(t, stack.Observability.Trail, stack.CloudTrail)
+		assert.Equal(t, "Network", *stack.Network.Node().Id())
+	})
+}
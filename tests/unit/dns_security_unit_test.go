@@ -0,0 +1,170 @@
+package lib_test
+
+import (
+	"testing"
+
+	"github.com/TuringGpt/iac-test-automations/lib"
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/assertions"
+	"github.com/aws/jsii-runtime-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// synthDnsSecurity synthesizes a TapStack with the given network configuration
+func synthDnsSecurity(t *testing.T, id string, network *lib.NetworkConfig) (*lib.TapStack, assertions.Template) {
+	t.Helper()
+	app := awscdk.NewApp(nil)
+	stack := lib.NewTapStack(app, jsii.String(id), &lib.TapStackProps{
+		EnvironmentSuffix: jsii.String("dnssec"),
+		Network:           network,
+	})
+	return stack, assertions.Template_FromStack(stack.Stack, nil)
+}
+
+func TestDnsSecurity(t *testing.T) {
+	defer jsii.Close()
+
+	t.Run("is disabled by default", func(t *testing.T) {
+		// ARRANGE
+		stack, template := synthDnsSecurity(t, "TapStackNoDnsSecurity", nil)
+
+		// ASSERT
+		template.ResourceCountIs(jsii.String("AWS::Route53Resolver::ResolverQueryLoggingConfig"), jsii.Number(0))
+		template.ResourceCountIs(jsii.String("AWS::Route53Resolver::FirewallRuleGroup"), jsii.Number(0))
+		assert.Nil(t, stack.Observability.QueryLogConfig)
+		assert.Nil(t, stack.Observability.DnsFirewallAlarm)
+	})
+
+	t.Run("logs queries to the logging bucket", func(t *testing.T) {
+		// ARRANGE
+		stack, template := synthDnsSecurity(t, "TapStackQueryLogsS3", &lib.NetworkConfig{
+			ResolverQueryLogs: lib.QueryLogDestinationS3,
+		})
+		bucket := logicalId(stack, stack.LoggingBucket.Node().DefaultChild().(awscdk.CfnElement))
+		vpc := logicalId(stack, stack.Vpc.Node().DefaultChild().(awscdk.CfnElement))
+
+		// ASSERT
+		template.HasResourceProperties(jsii.String("AWS::Route53Resolver::ResolverQueryLoggingConfig"), map[string]interface{}{
+			"DestinationArn": map[string]interface{}{
+				"Fn::Join": []interface{}{"", []interface{}{
+					map[string]interface{}{"Fn::GetAtt": []interface{}{bucket, "Arn"}},
+					"/resolver-query-logs",
+				}},
+			},
+		})
+		template.HasResourceProperties(jsii.String("AWS::Route53Resolver::ResolverQueryLoggingConfigAssociation"), map[string]interface{}{
+			"ResourceId": map[string]interface{}{"Ref": vpc},
+		})
+		template.HasResourceProperties(jsii.String("AWS::S3::BucketPolicy"), map[string]interface{}{
+			"Bucket": map[string]interface{}{"Ref": bucket},
+			"PolicyDocument": assertions.Match_ObjectLike(&map[string]interface{}{
+				"Statement": assertions.Match_ArrayWith(&[]interface{}{
+					assertions.Match_ObjectLike(&map[string]interface{}{
+						"Action":    "s3:PutObject",
+						"Principal": map[string]interface{}{"Service": "delivery.logs.amazonaws.com"},
+					}),
+				}),
+			}),
+		})
+		template.HasResourceProperties(jsii.String("AWS::KMS::Key"), map[string]interface{}{
+			"KeyPolicy": assertions.Match_ObjectLike(&map[string]interface{}{
+				"Statement": assertions.Match_ArrayWith(&[]interface{}{
+					assertions.Match_ObjectLike(&map[string]interface{}{
+						"Action":    "kms:GenerateDataKey*",
+						"Principal": map[string]interface{}{"Service": "delivery.logs.amazonaws.com"},
+					}),
+				}),
+			}),
+		})
+	})
+
+	t.Run("logs queries to an encrypted log group", func(t *testing.T) {
+		// ARRANGE
+		stack, template := synthDnsSecurity(t, "TapStackQueryLogsCw", &lib.NetworkConfig{
+			ResolverQueryLogs: lib.QueryLogDestinationCloudWatch,
+		})
+
+		// ASSERT
+		require.NotNil(t, stack.Observability.QueryLogGroup)
+		logGroup := logicalId(stack, stack.Observability.QueryLogGroup.Node().DefaultChild().(awscdk.CfnElement))
+		template.HasResourceProperties(jsii.String("AWS::Logs::LogGroup"), map[string]interface{}{
+			"LogGroupName": "/aws/route53resolver/prod-dnssec-query-logs",
+			"KmsKeyId":     assertions.Match_AnyValue(),
+		})
+		template.HasResourceProperties(jsii.String("AWS::Route53Resolver::ResolverQueryLoggingConfig"), map[string]interface{}{
+			"DestinationArn": map[string]interface{}{"Fn::GetAtt": []interface{}{logGroup, "Arn"}},
+		})
+	})
+
+	t.Run("associates a DNS Firewall with the VPC and alarms on blocked queries", func(t *testing.T) {
+		// ARRANGE
+		stack, template := synthDnsSecurity(t, "TapStackDnsFirewall", &lib.NetworkConfig{
+			DnsFirewall:               jsii.Bool(true),
+			DnsFirewallBlockedDomains: []string{"*"},
+			DnsFirewallAllowedDomains: []string{"*.amazonaws.com", "example.com"},
+		})
+		vpc := logicalId(stack, stack.Vpc.Node().DefaultChild().(awscdk.CfnElement))
+		ruleGroup := logicalId(stack, stack.Observability.DnsFirewallRuleGroup)
+
+		// ASSERT
+		template.HasResourceProperties(jsii.String("AWS::Route53Resolver::FirewallDomainList"), map[string]interface{}{
+			"Domains": []interface{}{"*"},
+		})
+		template.HasResourceProperties(jsii.String("AWS::Route53Resolver::FirewallRuleGroup"), map[string]interface{}{
+			"FirewallRules": []interface{}{
+				assertions.Match_ObjectLike(&map[string]interface{}{"Action": "BLOCK", "BlockResponse": "NODATA", "Priority": 200}),
+				assertions.Match_ObjectLike(&map[string]interface{}{"Action": "ALLOW", "Priority": 100}),
+			},
+		})
+		template.HasResourceProperties(jsii.String("AWS::Route53Resolver::FirewallRuleGroupAssociation"), map[string]interface{}{
+			"FirewallRuleGroupId": map[string]interface{}{"Fn::GetAtt": []interface{}{ruleGroup, "Id"}},
+			"VpcId":               map[string]interface{}{"Ref": vpc},
+		})
+		topic := logicalId(stack, stack.SNSAlerts.Node().DefaultChild().(awscdk.CfnElement))
+		template.HasResourceProperties(jsii.String("AWS::CloudWatch::Alarm"), map[string]interface{}{
+			"Namespace":    "AWS/Route53Resolver",
+			"MetricName":   "FirewallRuleQueryVolume",
+			"AlarmActions": []interface{}{map[string]interface{}{"Ref": topic}},
+		})
+	})
+
+	t.Run("rejects inconsistent DNS settings", func(t *testing.T) {
+		cases := []struct {
+			name    string
+			network *lib.NetworkConfig
+			message string
+		}{
+			{
+				name:    "unknown query log destination",
+				network: &lib.NetworkConfig{ResolverQueryLogs: "firehose"},
+				message: `unsupported ResolverQueryLogs "firehose"`,
+			},
+			{
+				name:    "firewall without blocked domains",
+				network: &lib.NetworkConfig{DnsFirewall: jsii.Bool(true)},
+				message: "DnsFirewall requires at least one DnsFirewallBlockedDomains entry",
+			},
+			{
+				name:    "invalid domain",
+				network: &lib.NetworkConfig{DnsFirewall: jsii.Bool(true), DnsFirewallBlockedDomains: []string{"bad domain.com"}},
+				message: `DNS Firewall domain "bad domain.com"`,
+			},
+			{
+				name:    "domains without the firewall",
+				network: &lib.NetworkConfig{DnsFirewallBlockedDomains: []string{"example.com"}},
+				message: "require DnsFirewall",
+			},
+		}
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				// ACT
+				err := (&lib.TapStackProps{Network: tc.network}).Validate()
+
+				// ASSERT
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.message)
+			})
+		}
+	})
+}
@@ -0,0 +1,141 @@
+package lib_test
+
+import (
+	"testing"
+
+	"github.com/TuringGpt/iac-test-automations/lib"
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/assertions"
+	"github.com/aws/jsii-runtime-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDns(t *testing.T) {
+	defer jsii.Close()
+
+	t.Run("creates a private zone without records by default", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		stack := lib.NewTapStack(app, jsii.String("TapStackDns"), &lib.TapStackProps{
+			EnvironmentSuffix: jsii.String("dns"),
+		})
+		template := assertions.Template_FromStack(stack.Stack, nil)
+
+		// ASSERT
+		require.NotNil(t, stack.PrivateHostedZone)
+		assert.Nil(t, stack.PublicHostedZone)
+		vpc := logicalId(stack, stack.Vpc.Node().DefaultChild().(awscdk.CfnElement))
+		template.HasResourceProperties(jsii.String("AWS::Route53::HostedZone"), map[string]interface{}{
+			"Name": "dns.internal.",
+			"VPCs": []interface{}{
+				assertions.Match_ObjectLike(&map[string]interface{}{"VPCId": map[string]interface{}{"Ref": vpc}}),
+			},
+		})
+		// The internet-facing ALB gets no private name
+		template.ResourceCountIs(jsii.String("AWS::Route53::RecordSet"), jsii.Number(0))
+		assert.Empty(t, stack.Dns.Records)
+		template.HasOutput(jsii.String("PrivateHostedZoneId"), map[string]interface{}{})
+		assert.Empty(t, *template.FindOutputs(jsii.String("PublicHostedZoneId"), nil))
+	})
+
+	t.Run("names the database writer and reader endpoints", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		stack := lib.NewTapStack(app, jsii.String("TapStackDnsDb"), &lib.TapStackProps{
+			EnvironmentSuffix: jsii.String("dns"),
+			DatabaseEngine:    lib.DatabaseEngineAuroraPostgres,
+			PrivateZoneName:   jsii.String("app.corp.internal"),
+		})
+		template := assertions.Template_FromStack(stack.Stack, nil)
+		cluster := logicalId(stack, stack.Database.Node().DefaultChild().(awscdk.CfnElement))
+
+		// ASSERT
+		template.HasResourceProperties(jsii.String("AWS::Route53::RecordSet"), map[string]interface{}{
+			"Name":            "db.app.corp.internal.",
+			"Type":            "CNAME",
+			"ResourceRecords": []interface{}{map[string]interface{}{"Fn::GetAtt": []interface{}{cluster, "Endpoint.Address"}}},
+		})
+		template.HasResourceProperties(jsii.String("AWS::Route53::RecordSet"), map[string]interface{}{
+			"Name":            "db-ro.app.corp.internal.",
+			"Type":            "CNAME",
+			"ResourceRecords": []interface{}{map[string]interface{}{"Fn::GetAtt": []interface{}{cluster, "ReadEndpoint.Address"}}},
+		})
+		assert.Contains(t, stack.Dns.Records, "db")
+		assert.Contains(t, stack.Dns.Records, "db-ro")
+	})
+
+	t.Run("points the custom CloudFront domain at the distribution from the public zone", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		env := &awscdk.Environment{Account: jsii.String("123456789012"), Region: jsii.String("eu-west-1")}
+		edge := lib.NewEdgeStack(app, jsii.String("EdgeDnsTest"), &lib.EdgeStackProps{
+			StackProps:        &awscdk.StackProps{Env: env},
+			EnvironmentSuffix: jsii.String("dns"),
+			CdnDomainName:     jsii.String("cdn.example.com"),
+		})
+		stack := lib.NewTapStack(app, jsii.String("TapStackDnsPublic"), &lib.TapStackProps{
+			StackProps:        &awscdk.StackProps{Env: env},
+			EnvironmentSuffix: jsii.String("dns"),
+			EdgeStack:         edge,
+			PublicZoneName:    jsii.String("example.com"),
+			Network:           &lib.NetworkConfig{DualStack: jsii.Bool(true)},
+		})
+		template := assertions.Template_FromStack(stack.Stack, nil)
+
+		// ASSERT
+		require.NotNil(t, stack.PublicHostedZone)
+		template.HasResourceProperties(jsii.String("AWS::Route53::HostedZone"), map[string]interface{}{
+			"Name": "example.com.",
+			"VPCs": assertions.Match_Absent(),
+		})
+		for _, recordType := range []string{"A", "AAAA"} {
+			template.HasResourceProperties(jsii.String("AWS::Route53::RecordSet"), map[string]interface{}{
+				"Name": "cdn.example.com.",
+				"Type": recordType,
+				"AliasTarget": assertions.Match_ObjectLike(&map[string]interface{}{
+					"HostedZoneId": assertions.Match_AnyValue(),
+					"DNSName": map[string]interface{}{
+						"Fn::GetAtt": []interface{}{logicalId(stack, stack.CloudFrontDist.Node().DefaultChild().(awscdk.CfnElement)), "DomainName"},
+					},
+				}),
+			})
+		}
+		template.HasOutput(jsii.String("PublicHostedZoneId"), map[string]interface{}{})
+		template.HasOutput(jsii.String("PublicHostedZoneNameServers"), map[string]interface{}{})
+	})
+
+	t.Run("rejects invalid zone settings", func(t *testing.T) {
+		app := awscdk.NewApp(nil)
+		edge := lib.NewEdgeStack(app, jsii.String("EdgeDnsValidation"), &lib.EdgeStackProps{
+			EnvironmentSuffix: jsii.String("dns"),
+			CdnDomainName:     jsii.String("cdn.example.org"),
+		})
+		cases := []struct {
+			name    string
+			props   *lib.TapStackProps
+			message string
+		}{
+			{
+				name:    "invalid private zone name",
+				props:   &lib.TapStackProps{PrivateZoneName: jsii.String("Dev_Internal")},
+				message: `PrivateZoneName "Dev_Internal" is not a valid lower-case DNS name`,
+			},
+			{
+				name:    "CloudFront domain outside the public zone",
+				props:   &lib.TapStackProps{PublicZoneName: jsii.String("example.com"), EdgeStack: edge},
+				message: `CdnDomainName "cdn.example.org" is not inside PublicZoneName "example.com"`,
+			},
+		}
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				// ACT
+				err := tc.props.Validate()
+
+				// ASSERT
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.message)
+			})
+		}
+	})
+}
@@ -0,0 +1,129 @@
+package lib_test
+
+import (
+	"testing"
+
+	"github.com/TuringGpt/iac-test-automations/lib"
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/assertions"
+	"github.com/aws/jsii-runtime-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDualStack(t *testing.T) {
+	defer jsii.Close()
+
+	t.Run("is IPv4-only by default", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		stack := lib.NewTapStack(app, jsii.String("TapStackIpv4"), &lib.TapStackProps{
+			EnvironmentSuffix: jsii.String("ipv6"),
+		})
+		template := assertions.Template_FromStack(stack.Stack, nil)
+
+		// ASSERT
+		assert.False(t, stack.Network.DualStack)
+		template.ResourceCountIs(jsii.String("AWS::EC2::VPCCidrBlock"), jsii.Number(0))
+		template.ResourceCountIs(jsii.String("AWS::EC2::EgressOnlyInternetGateway"), jsii.Number(0))
+		template.HasResourceProperties(jsii.String("AWS::CloudFront::Distribution"), map[string]interface{}{
+			"DistributionConfig": assertions.Match_ObjectLike(&map[string]interface{}{"IPV6Enabled": false}),
+		})
+	})
+
+	t.Run("adds IPv6 end to end when enabled", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		stack := lib.NewTapStack(app, jsii.String("TapStackDualStack"), &lib.TapStackProps{
+			EnvironmentSuffix: jsii.String("ipv6"),
+			Network:           &lib.NetworkConfig{DualStack: jsii.Bool(true)},
+		})
+		template := assertions.Template_FromStack(stack.Stack, nil)
+
+		// ASSERT - VPC and subnets
+		require.True(t, stack.Network.DualStack)
+		template.HasResourceProperties(jsii.String("AWS::EC2::VPCCidrBlock"), map[string]interface{}{
+			"AmazonProvidedIpv6CidrBlock": true,
+		})
+		subnets := template.FindResources(jsii.String("AWS::EC2::Subnet"), map[string]interface{}{
+			"Properties": map[string]interface{}{
+				"Ipv6CidrBlock":               assertions.Match_AnyValue(),
+				"AssignIpv6AddressOnCreation": true,
+			},
+		})
+		assert.Len(t, *subnets, 6)
+
+		// ASSERT - public subnets use the internet gateway, private ones the egress-only gateway
+		template.ResourceCountIs(jsii.String("AWS::EC2::EgressOnlyInternetGateway"), jsii.Number(1))
+		publicRoutes := template.FindResources(jsii.String("AWS::EC2::Route"), map[string]interface{}{
+			"Properties": map[string]interface{}{
+				"DestinationIpv6CidrBlock": "::/0",
+				"GatewayId":                assertions.Match_AnyValue(),
+			},
+		})
+		assert.Len(t, *publicRoutes, 2)
+		privateRoutes := template.FindResources(jsii.String("AWS::EC2::Route"), map[string]interface{}{
+			"Properties": map[string]interface{}{
+				"DestinationIpv6CidrBlock":    "::/0",
+				"EgressOnlyInternetGatewayId": assertions.Match_AnyValue(),
+			},
+		})
+		assert.Len(t, *privateRoutes, 2)
+
+		// ASSERT - security groups, ALB and CloudFront
+		template.HasResourceProperties(jsii.String("AWS::EC2::SecurityGroup"), map[string]interface{}{
+			"GroupDescription": "Security group for the internet-facing Application Load Balancer",
+			"SecurityGroupIngress": assertions.Match_ArrayWith(&[]interface{}{
+				assertions.Match_ObjectLike(&map[string]interface{}{"CidrIpv6": "::/0", "FromPort": 80}),
+			}),
+		})
+		template.HasResourceProperties(jsii.String("AWS::EC2::SecurityGroup"), map[string]interface{}{
+			"GroupDescription": "Security group for EC2 instances in private subnets",
+			"SecurityGroupEgress": assertions.Match_ArrayWith(&[]interface{}{
+				assertions.Match_ObjectLike(&map[string]interface{}{"CidrIpv6": "::/0"}),
+			}),
+		})
+		template.HasResourceProperties(jsii.String("AWS::ElasticLoadBalancingV2::LoadBalancer"), map[string]interface{}{
+			"IpAddressType": "dualstack",
+		})
+		template.HasResourceProperties(jsii.String("AWS::CloudFront::Distribution"), map[string]interface{}{
+			"DistributionConfig": assertions.Match_ObjectLike(&map[string]interface{}{"IPV6Enabled": true}),
+		})
+	})
+
+	t.Run("keeps the private tier egress-less without NAT", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		stack := lib.NewTapStack(app, jsii.String("TapStackDualStackNoNat"), &lib.TapStackProps{
+			EnvironmentSuffix: jsii.String("ipv6"),
+			Network: &lib.NetworkConfig{
+				DualStack:    jsii.Bool(true),
+				NatMode:      lib.NatModeNone,
+				VpcEndpoints: jsii.Bool(true),
+			},
+		})
+		template := assertions.Template_FromStack(stack.Stack, nil)
+
+		// ASSERT
+		template.ResourceCountIs(jsii.String("AWS::EC2::EgressOnlyInternetGateway"), jsii.Number(0))
+		assert.Nil(t, stack.Network.EgressOnlyInternetGateway)
+	})
+
+	t.Run("cannot be combined with an existing VPC", func(t *testing.T) {
+		// ARRANGE
+		props := &lib.TapStackProps{
+			StackProps: &awscdk.StackProps{
+				Env: &awscdk.Environment{Account: jsii.String("123456789012"), Region: jsii.String("eu-west-1")},
+			},
+			Network:     &lib.NetworkConfig{DualStack: jsii.Bool(true)},
+			ExistingVpc: &lib.ExistingVpcConfig{VpcId: jsii.String("vpc-0123456789abcdef0")},
+		}
+
+		// ACT
+		err := props.Validate()
+
+		// ASSERT
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "DualStack cannot add IPv6 to an ExistingVpc")
+	})
+}
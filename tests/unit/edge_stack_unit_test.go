@@ -0,0 +1,181 @@
+package lib_test
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/TuringGpt/iac-test-automations/lib"
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/assertions"
+	"github.com/aws/jsii-runtime-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEdgeStack(t *testing.T) {
+	defer jsii.Close()
+
+	t.Run("pins edge resources to us-east-1", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		edge := lib.NewEdgeStack(app, jsii.String("EdgeTest"), &lib.EdgeStackProps{
+			StackProps: &awscdk.StackProps{
+				Env: &awscdk.Environment{
+					Account: jsii.String("123456789012"),
+					Region:  jsii.String("eu-west-1"),
+				},
+			},
+			EnvironmentSuffix: jsii.String("edge-test"),
+			CdnDomainName:     jsii.String("cdn.example.com"),
+		})
+		template := assertions.Template_FromStack(edge.Stack, nil)
+
+		// ASSERT - Region is forced to us-east-1
+		assert.Equal(t, lib.EdgeRegion, *edge.Region())
+		assert.Equal(t, "123456789012", *edge.Account())
+
+		// ASSERT - CLOUDFRONT-scoped WebACL and certificate live in the edge stack
+		template.ResourceCountIs(jsii.String("AWS::WAFv2::WebACL"), jsii.Number(1))
+		template.HasResourceProperties(jsii.String("AWS::WAFv2::WebACL"), map[string]interface{}{
+			"Name":  "prod-edge-test-waf",
+			"Scope": "CLOUDFRONT",
+		})
+		template.HasResourceProperties(jsii.String("AWS::CertificateManager::Certificate"), map[string]interface{}{
+			"DomainName":       "cdn.example.com",
+			"ValidationMethod": "DNS",
+		})
+	})
+
+	t.Run("skips the certificate without a custom domain", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		edge := lib.NewEdgeStack(app, jsii.String("EdgeNoDomainTest"), &lib.EdgeStackProps{
+			EnvironmentSuffix: jsii.String("edge-nodomain"),
+		})
+		template := assertions.Template_FromStack(edge.Stack, nil)
+
+		// ASSERT
+		assert.Nil(t, edge.Certificate)
+		template.ResourceCountIs(jsii.String("AWS::CertificateManager::Certificate"), jsii.Number(0))
+	})
+
+	t.Run("blocks IPv4 and IPv6 ranges with one IP set per address family", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		edge := lib.NewEdgeStack(app, jsii.String("EdgeBlockedTest"), &lib.EdgeStackProps{
+			EnvironmentSuffix: jsii.String("edge-blocked"),
+			BlockedCidrs:      []string{"203.0.113.0/24", "2001:db8::/32"},
+		})
+		stack := lib.NewTapStack(app, jsii.String("TapBlockedTest"), &lib.TapStackProps{
+			EnvironmentSuffix: jsii.String("edge-blocked"),
+			Cdn:               &lib.CdnConfig{BlockedCidrs: []string{"2001:db8::/32"}},
+		})
+
+		for _, s := range []awscdk.Stack{edge.Stack, stack.Stack} {
+			template := assertions.Template_FromStack(s, nil)
+
+			// ACT
+			ipSets := template.FindResources(jsii.String("AWS::WAFv2::IPSet"), nil)
+			ids := make([]string, 0, len(*ipSets))
+			for id, ipSet := range *ipSets {
+				assert.Equal(t, "CLOUDFRONT", (*ipSet)["Properties"].(map[string]interface{})["Scope"])
+				ids = append(ids, id)
+			}
+			sort.Strings(ids)
+			var refs []interface{}
+			for _, id := range ids {
+				refs = append(refs, map[string]interface{}{
+					"IPSetReferenceStatement": map[string]interface{}{
+						"Arn": map[string]interface{}{"Fn::GetAtt": []interface{}{id, "Arn"}},
+					},
+				})
+			}
+
+			// ASSERT - both sets exist and the first rule blocks either of them
+			assert.Len(t, *ipSets, 2)
+			template.HasResourceProperties(jsii.String("AWS::WAFv2::IPSet"), map[string]interface{}{
+				"IPAddressVersion": "IPV6",
+				"Addresses":        []interface{}{"2001:db8::/32"},
+			})
+			template.HasResourceProperties(jsii.String("AWS::WAFv2::WebACL"), map[string]interface{}{
+				"Rules": assertions.Match_ArrayWith(&[]interface{}{
+					assertions.Match_ObjectLike(&map[string]interface{}{
+						"Name":      "BlockedCidrs",
+						"Priority":  0,
+						"Action":    map[string]interface{}{"Block": map[string]interface{}{}},
+						"Statement": map[string]interface{}{"OrStatement": map[string]interface{}{"Statements": assertions.Match_ArrayWith(&refs)}},
+					}),
+				}),
+			})
+		}
+		assertions.Template_FromStack(edge.Stack, nil).HasResourceProperties(jsii.String("AWS::WAFv2::IPSet"), map[string]interface{}{
+			"IPAddressVersion": "IPV4",
+			"Addresses":        []interface{}{"203.0.113.0/24"},
+		})
+	})
+
+	t.Run("creates no IP sets without blocked ranges", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		edge := lib.NewEdgeStack(app, jsii.String("EdgeNoBlockedTest"), &lib.EdgeStackProps{
+			EnvironmentSuffix: jsii.String("edge-open"),
+		})
+		template := assertions.Template_FromStack(edge.Stack, nil)
+
+		// ASSERT
+		template.ResourceCountIs(jsii.String("AWS::WAFv2::IPSet"), jsii.Number(0))
+	})
+
+	t.Run("returns an error for an invalid blocked range", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+
+		// ACT
+		edge, err := lib.NewEdgeStackE(app, jsii.String("EdgeInvalidBlockedTest"), &lib.EdgeStackProps{
+			EnvironmentSuffix: jsii.String("edge-invalid"),
+			BlockedCidrs:      []string{"203.0.113.0"},
+		})
+
+		// ASSERT
+		assert.Nil(t, edge)
+		assert.EqualError(t, err, `invalid EdgeStackProps: BlockedCidrs entry "203.0.113.0" is not a valid CIDR block`)
+	})
+
+	t.Run("references edge resources from a TapStack in another region", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		env := &awscdk.Environment{
+			Account: jsii.String("123456789012"),
+			Region:  jsii.String("eu-west-1"),
+		}
+		edge := lib.NewEdgeStack(app, jsii.String("EdgeCrossRegionTest"), &lib.EdgeStackProps{
+			StackProps:        &awscdk.StackProps{Env: env},
+			EnvironmentSuffix: jsii.String("xregion-test"),
+			CdnDomainName:     jsii.String("cdn.example.com"),
+		})
+		stack := lib.NewTapStack(app, jsii.String("TapCrossRegionTest"), &lib.TapStackProps{
+			StackProps:        &awscdk.StackProps{Env: env},
+			EnvironmentSuffix: jsii.String("xregion-test"),
+			EdgeStack:         edge,
+		})
+		template := assertions.Template_FromStack(stack.Stack, nil)
+
+		// ASSERT - The main stack creates no WebACL of its own
+		template.ResourceCountIs(jsii.String("AWS::WAFv2::WebACL"), jsii.Number(0))
+		template.ResourceCountIs(jsii.String("AWS::CertificateManager::Certificate"), jsii.Number(0))
+		assert.Equal(t, edge.WAF, stack.WAF)
+
+		// ASSERT - Distribution uses the edge WebACL and certificate
+		template.HasResourceProperties(jsii.String("AWS::CloudFront::Distribution"), map[string]interface{}{
+			"DistributionConfig": map[string]interface{}{
+				"Aliases":  []interface{}{"cdn.example.com"},
+				"WebACLId": assertions.Match_AnyValue(),
+				"ViewerCertificate": map[string]interface{}{
+					"AcmCertificateArn": assertions.Match_AnyValue(),
+				},
+			},
+		})
+
+		// ASSERT - Cross-region references are resolved by a reader custom resource
+		template.ResourceCountIs(jsii.String("Custom::CrossRegionExportReader"), jsii.Number(1))
+	})
+}
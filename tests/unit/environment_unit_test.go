@@ -0,0 +1,181 @@
+package lib_test
+
+import (
+	"testing"
+
+	"github.com/TuringGpt/iac-test-automations/lib"
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/jsii-runtime-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvironmentSuffix(t *testing.T) {
+	defer jsii.Close()
+
+	tests := []struct {
+		name     string
+		explicit *string
+		context  interface{}
+		envVar   string
+		want     string
+		wantErr  string
+	}{
+		{
+			name: "defaults to dev",
+			want: "dev",
+		},
+		{
+			name:   "reads the environment variable",
+			envVar: "pr42",
+			want:   "pr42",
+		},
+		{
+			name:    "context wins over the environment variable",
+			context: "staging",
+			envVar:  "pr42",
+			want:    "staging",
+		},
+		{
+			name:     "props win over context and environment variable",
+			explicit: jsii.String("prod"),
+			context:  "staging",
+			envVar:   "pr42",
+			want:     "prod",
+		},
+		{
+			name:    "rejects a non-string context value",
+			context: 42,
+			wantErr: `context "environmentSuffix" must be a string, got float64`,
+		},
+		{
+			name:     "rejects uppercase letters",
+			explicit: jsii.String("Dev"),
+			wantErr:  `environment suffix from props: "Dev" must be lowercase letters, digits and hyphens`,
+		},
+		{
+			name:    "rejects characters unsafe for bucket names",
+			context: "feature_x",
+			wantErr: `environment suffix from context: "feature_x" must be lowercase letters`,
+		},
+		{
+			name:     "rejects a trailing hyphen",
+			explicit: jsii.String("pr-"),
+			wantErr:  `"pr-" must be lowercase letters`,
+		},
+		{
+			name:     "rejects an empty suffix",
+			explicit: jsii.String(""),
+			wantErr:  `environment suffix from props: "" must be lowercase letters`,
+		},
+		{
+			name:    "rejects suffixes longer than the limit",
+			envVar:  "a-very-long-feature-branch",
+			wantErr: `environment suffix from ENVIRONMENT_SUFFIX: "a-very-long-feature-branch" is 26 characters, the maximum is 20`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// ARRANGE
+			t.Setenv(lib.EnvironmentSuffixEnvVar, tt.envVar)
+			appProps := &awscdk.AppProps{}
+			if tt.context != nil {
+				appProps.Context = &map[string]interface{}{
+					lib.EnvironmentSuffixContextKey: tt.context,
+				}
+			}
+			app := awscdk.NewApp(appProps)
+
+			// ACT
+			suffix, err := lib.ResolveEnvironmentSuffix(app, tt.explicit)
+
+			// ASSERT
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, suffix)
+		})
+	}
+
+	t.Run("stack reads the suffix from context without props", func(t *testing.T) {
+		// ARRANGE
+		t.Setenv(lib.EnvironmentSuffixEnvVar, "")
+		app := awscdk.NewApp(&awscdk.AppProps{
+			Context: &map[string]interface{}{lib.EnvironmentSuffixContextKey: "ctx-test"},
+		})
+
+		// ACT
+		stack := lib.NewTapStack(app, jsii.String("TapStackContextSuffixTest"), nil)
+
+		// ASSERT
+		assert.Equal(t, "ctx-test", *stack.EnvironmentSuffix)
+	})
+
+	t.Run("stack panics with a descriptive error for an invalid suffix", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+
+		// ASSERT
+		assert.PanicsWithValue(t, `invalid TapStackProps: environment suffix from props: "Bad_Suffix" must be lowercase letters, digits and hyphens, and start and end with a letter or digit`, func() {
+			lib.NewTapStack(app, jsii.String("TapStackBadSuffixTest"), &lib.TapStackProps{
+				EnvironmentSuffix: jsii.String("Bad_Suffix"),
+			})
+		})
+	})
+
+	t.Run("stack constructor returns the error for an invalid suffix", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+
+		// ACT
+		stack, err := lib.NewTapStackE(app, jsii.String("TapStackBadSuffixErrTest"), &lib.TapStackProps{
+			EnvironmentSuffix: jsii.String("Bad_Suffix"),
+		})
+
+		// ASSERT
+		require.Error(t, err)
+		assert.Nil(t, stack)
+		assert.Contains(t, err.Error(), `environment suffix from props: "Bad_Suffix"`)
+	})
+
+	t.Run("edge stack constructor returns the error for an invalid suffix", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+
+		// ACT
+		stack, err := lib.NewEdgeStackE(app, jsii.String("TapEdgeStackBadSuffixErrTest"), &lib.EdgeStackProps{
+			EnvironmentSuffix: jsii.String("Bad_Suffix"),
+		})
+
+		// ASSERT
+		require.Error(t, err)
+		assert.Nil(t, stack)
+		assert.Contains(t, err.Error(), `invalid EdgeStackProps: environment suffix from props: "Bad_Suffix"`)
+		assert.Panics(t, func() {
+			lib.NewEdgeStack(app, jsii.String("TapEdgeStackBadSuffixTest"), &lib.EdgeStackProps{
+				EnvironmentSuffix: jsii.String("Bad_Suffix"),
+			})
+		})
+	})
+
+	t.Run("stack constructor returns the error for an unknown database engine", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+
+		// ACT
+		stack, err := lib.NewTapStackE(app, jsii.String("TapStackBadEngineErrTest"), &lib.TapStackProps{
+			EnvironmentSuffix: jsii.String("engine"),
+			DatabaseEngine:    "postgres",
+		})
+
+		// ASSERT
+		require.Error(t, err)
+		assert.Nil(t, stack)
+		assert.Contains(t, err.Error(), `unsupported DatabaseEngine "postgres"`)
+		assert.Empty(t, *app.Node().Children())
+	})
+}
@@ -0,0 +1,209 @@
+package lib_test
+
+import (
+	"testing"
+
+	"github.com/TuringGpt/iac-test-automations/lib"
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/assertions"
+	"github.com/aws/jsii-runtime-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const existingKeyArn = "arn:aws:kms:eu-west-1:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab"
+
+func TestExistingResources(t *testing.T) {
+	defer jsii.Close()
+
+	env := &awscdk.Environment{Account: jsii.String("123456789012"), Region: jsii.String("eu-west-1")}
+
+	t.Run("imports the VPC and skips flow logs it already has", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		stack := lib.NewTapStack(app, jsii.String("TapStackExistingVpc"), &lib.TapStackProps{
+			StackProps:        &awscdk.StackProps{Env: env},
+			EnvironmentSuffix: jsii.String("byo"),
+			ExistingVpc: &lib.ExistingVpcConfig{
+				VpcId:       jsii.String("vpc-0123456789abcdef0"),
+				HasFlowLogs: true,
+			},
+		})
+		template := assertions.Template_FromStack(stack.Stack, nil)
+
+		// ASSERT
+		template.ResourceCountIs(jsii.String("AWS::EC2::VPC"), jsii.Number(0))
+		template.ResourceCountIs(jsii.String("AWS::EC2::Subnet"), jsii.Number(0))
+		template.ResourceCountIs(jsii.String("AWS::EC2::FlowLog"), jsii.Number(0))
+		assert.True(t, stack.Network.Imported)
+		assert.Nil(t, stack.Observability.FlowLogsBucket)
+		assert.NotEmpty(t, *stack.PrivateSubnets)
+	})
+
+	t.Run("keeps flow logs for an imported VPC without them", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		stack := lib.NewTapStack(app, jsii.String("TapStackExistingVpcNoLogs"), &lib.TapStackProps{
+			StackProps:        &awscdk.StackProps{Env: env},
+			EnvironmentSuffix: jsii.String("byo"),
+			ExistingVpc: &lib.ExistingVpcConfig{
+				Tags: map[string]string{"Name": "shared-vpc"},
+			},
+		})
+		template := assertions.Template_FromStack(stack.Stack, nil)
+
+		// ASSERT
+		template.ResourceCountIs(jsii.String("AWS::EC2::VPC"), jsii.Number(0))
+		template.ResourceCountIs(jsii.String("AWS::EC2::FlowLog"), jsii.Number(1))
+	})
+
+	t.Run("encrypts with an imported KMS key", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		stack := lib.NewTapStack(app, jsii.String("TapStackExistingKey"), &lib.TapStackProps{
+			EnvironmentSuffix: jsii.String("byo"),
+			ExistingKmsKeyArn: jsii.String(existingKeyArn),
+		})
+		template := assertions.Template_FromStack(stack.Stack, nil)
+
+		// ASSERT
+		template.ResourceCountIs(jsii.String("AWS::KMS::Key"), jsii.Number(0))
+		template.ResourceCountIs(jsii.String("AWS::KMS::Alias"), jsii.Number(0))
+		template.HasResourceProperties(jsii.String("AWS::S3::Bucket"), map[string]interface{}{
+			"BucketEncryption": map[string]interface{}{
+				"ServerSideEncryptionConfiguration": []interface{}{
+					map[string]interface{}{
+						"ServerSideEncryptionByDefault": map[string]interface{}{
+							"KMSMasterKeyID": existingKeyArn,
+							"SSEAlgorithm":   "aws:kms",
+						},
+					},
+				},
+			},
+		})
+	})
+
+	t.Run("rejects invalid existing resource settings", func(t *testing.T) {
+		cases := []struct {
+			name    string
+			props   *lib.TapStackProps
+			message string
+		}{
+			{
+				name: "VPC without ID or tags",
+				props: &lib.TapStackProps{
+					StackProps:  &awscdk.StackProps{Env: env},
+					ExistingVpc: &lib.ExistingVpcConfig{},
+				},
+				message: "ExistingVpc requires VpcId or Tags",
+			},
+			{
+				name: "VPC lookup without an environment",
+				props: &lib.TapStackProps{
+					ExistingVpc: &lib.ExistingVpcConfig{VpcId: jsii.String("vpc-0123456789abcdef0")},
+				},
+				message: "explicit Account and Region",
+			},
+			{
+				name:    "malformed key ARN",
+				props:   &lib.TapStackProps{ExistingKmsKeyArn: jsii.String("alias/shared")},
+				message: "is not a KMS key ARN",
+			},
+		}
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				// ACT
+				err := tc.props.Validate()
+
+				// ASSERT
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.message)
+			})
+		}
+	})
+
+	t.Run("maps subnet groups of the imported VPC to tiers", func(t *testing.T) {
+		// ARRANGE - a cached lookup result with "web" and "app" groups and no isolated tier
+		app := awscdk.NewApp(&awscdk.AppProps{
+			Context: &map[string]interface{}{vpcLookupContextKey: webAppVpc()},
+		})
+		stack := lib.NewTapStack(app, jsii.String("TapStackExistingVpcGroups"), &lib.TapStackProps{
+			StackProps:        &awscdk.StackProps{Env: env},
+			EnvironmentSuffix: jsii.String("byo"),
+			ExistingVpc: &lib.ExistingVpcConfig{
+				VpcId:                  jsii.String("vpc-0123456789abcdef0"),
+				PublicSubnetGroupName:  jsii.String("web"),
+				PrivateSubnetGroupName: jsii.String("app"),
+			},
+		})
+
+		// ASSERT
+		require.Len(t, *stack.PublicSubnets, 2)
+		require.Len(t, *stack.PrivateSubnets, 2)
+		assert.Equal(t, "subnet-web-a", *(*stack.PublicSubnets)[0].SubnetId())
+		assert.Equal(t, "subnet-app-a", *(*stack.PrivateSubnets)[0].SubnetId())
+		assert.Empty(t, *stack.IsolatedSubnets)
+	})
+
+	t.Run("requires isolated subnets for the database", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(&awscdk.AppProps{
+			Context: &map[string]interface{}{vpcLookupContextKey: webAppVpc()},
+		})
+
+		// ASSERT
+		assert.PanicsWithValue(t, "invalid TapStackProps: DatabaseEngine requires isolated subnets in the VPC", func() {
+			lib.NewTapStack(app, jsii.String("TapStackExistingVpcDb"), &lib.TapStackProps{
+				StackProps:        &awscdk.StackProps{Env: env},
+				EnvironmentSuffix: jsii.String("byo"),
+				DatabaseEngine:    lib.DatabaseEngineAuroraPostgres,
+				ExistingVpc:       &lib.ExistingVpcConfig{VpcId: jsii.String("vpc-0123456789abcdef0")},
+			})
+		})
+	})
+
+	t.Run("requires private subnets for the Instance Connect Endpoint", func(t *testing.T) {
+		// ARRANGE - a VPC with the "web" public tier only
+		publicOnly := webAppVpc()
+		publicOnly["subnetGroups"] = publicOnly["subnetGroups"].([]interface{})[:1]
+		app := awscdk.NewApp(&awscdk.AppProps{
+			Context: &map[string]interface{}{vpcLookupContextKey: publicOnly},
+		})
+
+		// ACT
+		stack, err := lib.NewTapStackE(app, jsii.String("TapStackExistingVpcEice"), &lib.TapStackProps{
+			StackProps:        &awscdk.StackProps{Env: env},
+			EnvironmentSuffix: jsii.String("byo"),
+			Compute:           &lib.ComputeConfig{InstanceConnectEndpoint: jsii.Bool(true)},
+			ExistingVpc:       &lib.ExistingVpcConfig{VpcId: jsii.String("vpc-0123456789abcdef0")},
+		})
+
+		// ASSERT
+		require.EqualError(t, err, "invalid TapStackProps: InstanceConnectEndpoint requires private subnets in the VPC")
+		assert.Nil(t, stack)
+		assert.Nil(t, app.Node().TryFindChild(jsii.String("TapStackExistingVpcEice")), "the partial stack is removed")
+	})
+}
+
+// vpcLookupContextKey is where Vpc_FromLookup caches vpc-0123456789abcdef0 in 123456789012/eu-west-1
+const vpcLookupContextKey = "vpc-provider:account=123456789012:filter.vpc-id=vpc-0123456789abcdef0:region=eu-west-1:returnAsymmetricSubnets=true"
+
+// webAppVpc is a cached lookup result with public "web" and private "app" subnet groups
+func webAppVpc() map[string]interface{} {
+	group := func(name, groupType string) map[string]interface{} {
+		return map[string]interface{}{
+			"name": name,
+			"type": groupType,
+			"subnets": []interface{}{
+				map[string]interface{}{"subnetId": "subnet-" + name + "-a", "availabilityZone": "eu-west-1a", "routeTableId": "rtb-" + name + "-a", "cidr": "10.0.0.0/24"},
+				map[string]interface{}{"subnetId": "subnet-" + name + "-b", "availabilityZone": "eu-west-1b", "routeTableId": "rtb-" + name + "-b", "cidr": "10.0.1.0/24"},
+			},
+		}
+	}
+	return map[string]interface{}{
+		"vpcId":             "vpc-0123456789abcdef0",
+		"vpcCidrBlock":      "10.0.0.0/16",
+		"availabilityZones": []interface{}{},
+		"subnetGroups":      []interface{}{group("web", "Public"), group("app", "Private")},
+	}
+}
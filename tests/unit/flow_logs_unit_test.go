@@ -0,0 +1,151 @@
+package lib_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/TuringGpt/iac-test-automations/lib"
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/assertions"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslogs"
+	"github.com/aws/jsii-runtime-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlowLogs(t *testing.T) {
+	defer jsii.Close()
+
+	t.Run("delivers the custom format to S3 as hourly Hive-partitioned Parquet", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		stack := lib.NewTapStack(app, jsii.String("TapStackFlowLogs"), &lib.TapStackProps{
+			EnvironmentSuffix: jsii.String("flow"),
+		})
+		template := assertions.Template_FromStack(stack.Stack, nil)
+
+		// ACT
+		var fields []string
+		for _, field := range lib.FlowLogFields {
+			fields = append(fields, "${"+field.Name+"}")
+		}
+
+		// ASSERT
+		template.ResourceCountIs(jsii.String("AWS::EC2::FlowLog"), jsii.Number(1))
+		template.HasResourceProperties(jsii.String("AWS::EC2::FlowLog"), map[string]interface{}{
+			"LogDestinationType": "s3",
+			"LogFormat":          strings.Join(fields, " "),
+			"DestinationOptions": map[string]interface{}{
+				"fileFormat":               "parquet",
+				"hiveCompatiblePartitions": true,
+				"perHourPartition":         true,
+			},
+		})
+		for _, field := range []string{"pkt-srcaddr", "pkt-dstaddr", "tcp-flags", "flow-direction"} {
+			assert.Contains(t, fields, "${"+field+"}")
+		}
+		assert.Nil(t, stack.Observability.FlowLogGroup)
+	})
+
+	t.Run("registers a partition-projected Glue table and an encrypted Athena workgroup", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		stack := lib.NewTapStack(app, jsii.String("TapStackFlowLogTable"), &lib.TapStackProps{
+			EnvironmentSuffix: jsii.String("flow"),
+		})
+		template := assertions.Template_FromStack(stack.Stack, nil)
+		database := logicalId(stack, stack.Analytics.Database)
+		results := logicalId(stack, stack.Analytics.ResultsBucket.Node().DefaultChild().(awscdk.CfnElement))
+		key := logicalId(stack, stack.KmsKey.Node().DefaultChild().(awscdk.CfnElement))
+
+		// ASSERT
+		template.HasResourceProperties(jsii.String("AWS::Glue::Database"), map[string]interface{}{
+			"DatabaseInput": map[string]interface{}{
+				"Name":        "prod_flow_logs",
+				"Description": assertions.Match_AnyValue(),
+			},
+		})
+		template.HasResourceProperties(jsii.String("AWS::Glue::Table"), map[string]interface{}{
+			"DatabaseName": map[string]interface{}{"Ref": database},
+			"TableInput": assertions.Match_ObjectLike(&map[string]interface{}{
+				"Name": "vpc_flow_logs",
+				"PartitionKeys": []interface{}{
+					map[string]interface{}{"Name": "year", "Type": "string"},
+					map[string]interface{}{"Name": "month", "Type": "string"},
+					map[string]interface{}{"Name": "day", "Type": "string"},
+					map[string]interface{}{"Name": "hour", "Type": "string"},
+				},
+				"Parameters": assertions.Match_ObjectLike(&map[string]interface{}{
+					"classification":     "parquet",
+					"projection.enabled": "true",
+				}),
+				"StorageDescriptor": assertions.Match_ObjectLike(&map[string]interface{}{
+					"Columns": assertions.Match_ArrayWith(&[]interface{}{
+						map[string]interface{}{"Name": "pkt_srcaddr", "Type": "string"},
+						map[string]interface{}{"Name": "tcp_flags", "Type": "int"},
+						map[string]interface{}{"Name": "flow_direction", "Type": "string"},
+					}),
+					"SerdeInfo": map[string]interface{}{
+						"SerializationLibrary": "org.apache.hadoop.hive.ql.io.parquet.serde.ParquetHiveSerDe",
+					},
+				}),
+			}),
+		})
+		template.HasResourceProperties(jsii.String("AWS::Athena::WorkGroup"), map[string]interface{}{
+			"WorkGroupConfiguration": assertions.Match_ObjectLike(&map[string]interface{}{
+				"EnforceWorkGroupConfiguration": true,
+				"ResultConfiguration": map[string]interface{}{
+					"OutputLocation": map[string]interface{}{
+						"Fn::Join": []interface{}{"", []interface{}{"s3://", map[string]interface{}{"Ref": results}, "/"}},
+					},
+					"EncryptionConfiguration": map[string]interface{}{
+						"EncryptionOption": "SSE_KMS",
+						"KmsKey":           map[string]interface{}{"Fn::GetAtt": []interface{}{key, "Arn"}},
+					},
+				},
+			}),
+		})
+		template.HasOutput(jsii.String("AthenaWorkGroupName"), map[string]interface{}{})
+	})
+
+	t.Run("also delivers to an encrypted log group when enabled", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		stack := lib.NewTapStack(app, jsii.String("TapStackFlowLogsCloudWatch"), &lib.TapStackProps{
+			EnvironmentSuffix: jsii.String("flow"),
+			Network:           &lib.NetworkConfig{FlowLogsCloudWatch: jsii.Bool(true)},
+			Logging:           &lib.LoggingConfig{Retention: awslogs.RetentionDays_ONE_WEEK},
+		})
+		template := assertions.Template_FromStack(stack.Stack, nil)
+
+		// ASSERT
+		require.NotNil(t, stack.Observability.FlowLogGroup)
+		logGroup := logicalId(stack, stack.Observability.FlowLogGroup.Node().DefaultChild().(awscdk.CfnElement))
+		template.ResourceCountIs(jsii.String("AWS::EC2::FlowLog"), jsii.Number(2))
+		template.HasResourceProperties(jsii.String("AWS::Logs::LogGroup"), map[string]interface{}{
+			"LogGroupName":    "/aws/vpc-flow-logs/prod-flow-vpc",
+			"RetentionInDays": 7,
+			"KmsKeyId":        assertions.Match_AnyValue(),
+		})
+		template.HasResourceProperties(jsii.String("AWS::EC2::FlowLog"), map[string]interface{}{
+			"LogDestinationType": "cloud-watch-logs",
+			"LogGroupName":       map[string]interface{}{"Ref": logGroup},
+			"LogFormat":          assertions.Match_StringLikeRegexp(jsii.String(`\$\{pkt-srcaddr\}`)),
+		})
+	})
+
+	t.Run("rejects CloudWatch delivery for a VPC with its own flow logs", func(t *testing.T) {
+		// ARRANGE
+		props := &lib.TapStackProps{
+			ExistingVpc: &lib.ExistingVpcConfig{VpcId: jsii.String("vpc-0123456789abcdef0"), HasFlowLogs: true},
+			Network:     &lib.NetworkConfig{FlowLogsCloudWatch: jsii.Bool(true)},
+		}
+
+		// ACT
+		err := props.Validate()
+
+		// ASSERT
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "FlowLogsCloudWatch requires the stack's own flow logs")
+	})
+}
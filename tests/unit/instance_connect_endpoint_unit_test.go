@@ -0,0 +1,98 @@
+package lib_test
+
+import (
+	"testing"
+
+	"github.com/TuringGpt/iac-test-automations/lib"
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/assertions"
+	"github.com/aws/jsii-runtime-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstanceConnectEndpoint(t *testing.T) {
+	defer jsii.Close()
+
+	t.Run("is disabled by default", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		stack := lib.NewTapStack(app, jsii.String("TapStackNoEice"), &lib.TapStackProps{
+			EnvironmentSuffix: jsii.String("eice"),
+		})
+		template := assertions.Template_FromStack(stack.Stack, nil)
+
+		// ASSERT
+		template.ResourceCountIs(jsii.String("AWS::EC2::InstanceConnectEndpoint"), jsii.Number(0))
+		assert.NotContains(t, stack.SecurityGroups, "eice")
+		assert.Empty(t, *template.FindOutputs(jsii.String("InstanceConnectEndpointId"), nil))
+	})
+
+	t.Run("creates the endpoint in a private subnet with its own security group", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		stack := lib.NewTapStack(app, jsii.String("TapStackEice"), &lib.TapStackProps{
+			EnvironmentSuffix: jsii.String("eice"),
+			Compute:           &lib.ComputeConfig{InstanceConnectEndpoint: jsii.Bool(true)},
+		})
+		template := assertions.Template_FromStack(stack.Stack, nil)
+
+		// ASSERT
+		require.NotNil(t, stack.Compute.InstanceConnectEndpoint)
+		require.Contains(t, stack.SecurityGroups, "eice")
+		privateSubnet := (*stack.PrivateSubnets)[0].Node().DefaultChild().(awscdk.CfnElement)
+		eiceSG := logicalId(stack, stack.SecurityGroups["eice"].Node().DefaultChild().(awscdk.CfnElement))
+		template.HasResourceProperties(jsii.String("AWS::EC2::InstanceConnectEndpoint"), map[string]interface{}{
+			"SubnetId": map[string]interface{}{"Ref": logicalId(stack, privateSubnet)},
+			"SecurityGroupIds": []interface{}{
+				map[string]interface{}{"Fn::GetAtt": []interface{}{eiceSG, "GroupId"}},
+			},
+		})
+		template.HasOutput(jsii.String("InstanceConnectEndpointId"), map[string]interface{}{
+			"Value": map[string]interface{}{"Ref": logicalId(stack, stack.Compute.InstanceConnectEndpoint)},
+		})
+	})
+
+	t.Run("makes the endpoint the only SSH source of the instances", func(t *testing.T) {
+		// ARRANGE - the allowlisted bastion would otherwise also reach the instances
+		app := awscdk.NewApp(nil)
+		stack := lib.NewTapStack(app, jsii.String("TapStackEiceOnly"), &lib.TapStackProps{
+			EnvironmentSuffix: jsii.String("eice"),
+			Compute: &lib.ComputeConfig{
+				InstanceConnectEndpoint: jsii.Bool(true),
+				BastionMode:             lib.BastionModeCidrAllowlist,
+				BastionAllowedCidrs:     []string{"203.0.113.0/24"},
+			},
+		})
+		template := assertions.Template_FromStack(stack.Stack, nil)
+		ec2SG := logicalId(stack, stack.SecurityGroups["ec2"].Node().DefaultChild().(awscdk.CfnElement))
+		eiceSG := logicalId(stack, stack.SecurityGroups["eice"].Node().DefaultChild().(awscdk.CfnElement))
+
+		// ACT
+		sshIngress := template.FindResources(jsii.String("AWS::EC2::SecurityGroupIngress"), map[string]interface{}{
+			"Properties": map[string]interface{}{
+				"GroupId":  map[string]interface{}{"Fn::GetAtt": []interface{}{ec2SG, "GroupId"}},
+				"FromPort": 22,
+			},
+		})
+
+		// ASSERT
+		require.Len(t, *sshIngress, 1)
+		for _, resource := range *sshIngress {
+			assert.Equal(t,
+				map[string]interface{}{"Fn::GetAtt": []interface{}{eiceSG, "GroupId"}},
+				(*resource)["Properties"].(map[string]interface{})["SourceSecurityGroupId"])
+		}
+		template.HasResourceProperties(jsii.String("AWS::EC2::SecurityGroup"), map[string]interface{}{
+			"GroupDescription":     "Security group for EC2 instances in private subnets",
+			"SecurityGroupIngress": assertions.Match_Absent(),
+		})
+		// The endpoint may only open SSH connections to the instances
+		template.HasResourceProperties(jsii.String("AWS::EC2::SecurityGroupEgress"), map[string]interface{}{
+			"GroupId":                    map[string]interface{}{"Fn::GetAtt": []interface{}{eiceSG, "GroupId"}},
+			"DestinationSecurityGroupId": map[string]interface{}{"Fn::GetAtt": []interface{}{ec2SG, "GroupId"}},
+			"FromPort":                   22,
+			"ToPort":                     22,
+		})
+	})
+}
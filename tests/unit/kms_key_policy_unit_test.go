@@ -0,0 +1,203 @@
+package lib_test
+
+import (
+	"testing"
+
+	"github.com/TuringGpt/iac-test-automations/lib"
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/assertions"
+	"github.com/aws/jsii-runtime-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKmsKeyPolicy(t *testing.T) {
+	defer jsii.Close()
+
+	// synthKeyPolicy synthesizes a stack with every feature that adds to the
+	// key policy and returns the template and the key's policy statements
+	synthKeyPolicy := func(t *testing.T, id string) (*lib.TapStack, assertions.Template, []map[string]interface{}) {
+		app := awscdk.NewApp(nil)
+		stack := lib.NewTapStack(app, jsii.String(id), &lib.TapStackProps{
+			EnvironmentSuffix: jsii.String("kms"),
+			Network: &lib.NetworkConfig{
+				NetworkFirewall:    jsii.Bool(true),
+				ResolverQueryLogs:  lib.QueryLogDestinationS3,
+				FlowLogsCloudWatch: jsii.Bool(true),
+			},
+		})
+		template := assertions.Template_FromStack(stack.Stack, nil)
+
+		keys := *template.FindResources(jsii.String("AWS::KMS::Key"), nil)
+		require.Len(t, keys, 1)
+		var statements []map[string]interface{}
+		for _, key := range keys {
+			policy := (*key)["Properties"].(map[string]interface{})["KeyPolicy"].(map[string]interface{})
+			for _, statement := range policy["Statement"].([]interface{}) {
+				statements = append(statements, statement.(map[string]interface{}))
+			}
+		}
+		return stack, template, statements
+	}
+
+	t.Run("conditions every service principal statement", func(t *testing.T) {
+		// ARRANGE
+		_, _, statements := synthKeyPolicy(t, "TapStackKeyPolicyConditions")
+
+		// ASSERT
+		services := 0
+		for _, statement := range statements {
+			principal, _ := statement["Principal"].(map[string]interface{})
+			if _, ok := principal["Service"]; !ok {
+				continue
+			}
+			services++
+			assert.NotEmptyf(t, statement["Condition"], "statement for %v has no condition", principal["Service"])
+		}
+		assert.GreaterOrEqual(t, services, 4)
+	})
+
+	t.Run("conditions every account usage statement", func(t *testing.T) {
+		// ARRANGE
+		_, _, statements := synthKeyPolicy(t, "TapStackKeyPolicyUsage")
+
+		// ASSERT - only key administration, which cannot use the key, is unconditioned
+		accounts := 0
+		for _, statement := range statements {
+			principal, _ := statement["Principal"].(map[string]interface{})
+			if _, ok := principal["AWS"]; !ok {
+				continue
+			}
+			accounts++
+			if statement["Sid"] == "KeyAdministration" {
+				assert.NotContains(t, statement["Action"], "kms:Decrypt")
+				continue
+			}
+			assert.NotEmptyf(t, statement["Condition"], "statement %v has no condition", statement["Sid"])
+		}
+		assert.GreaterOrEqual(t, accounts, 3)
+	})
+
+	t.Run("limits IAM usage to the account and the consuming services", func(t *testing.T) {
+		// ARRANGE
+		_, template, _ := synthKeyPolicy(t, "TapStackKeyPolicyViaService")
+		viaService := func(service string) map[string]interface{} {
+			return map[string]interface{}{
+				"Fn::Join": []interface{}{"", []interface{}{service + ".", map[string]interface{}{"Ref": "AWS::Region"}, ".amazonaws.com"}},
+			}
+		}
+
+		// ASSERT
+		template.HasResourceProperties(jsii.String("AWS::KMS::Key"), map[string]interface{}{
+			"KeyPolicy": assertions.Match_ObjectLike(&map[string]interface{}{
+				"Statement": assertions.Match_ArrayWith(&[]interface{}{
+					assertions.Match_ObjectLike(&map[string]interface{}{
+						"Sid": "KeyUsageThroughIam",
+						"Condition": map[string]interface{}{
+							"StringEquals": map[string]interface{}{
+								"kms:CallerAccount": map[string]interface{}{"Ref": "AWS::AccountId"},
+								"kms:ViaService": []interface{}{
+									viaService("s3"), viaService("rds"), viaService("logs"),
+									viaService("secretsmanager"), viaService("backup"),
+								},
+							},
+						},
+					}),
+				}),
+			}),
+		})
+	})
+
+	t.Run("never grants kms:* and rotates the key", func(t *testing.T) {
+		// ARRANGE
+		_, template, statements := synthKeyPolicy(t, "TapStackKeyPolicyRotation")
+
+		// ASSERT
+		template.HasResourceProperties(jsii.String("AWS::KMS::Key"), map[string]interface{}{
+			"EnableKeyRotation": true,
+		})
+		for _, statement := range statements {
+			actions := statement["Action"]
+			if list, ok := actions.([]interface{}); ok {
+				assert.NotContains(t, list, "kms:*")
+			} else {
+				assert.NotEqual(t, "kms:*", actions)
+			}
+		}
+	})
+
+	t.Run("binds CloudWatch Logs to the account's log groups", func(t *testing.T) {
+		// ARRANGE
+		_, template, _ := synthKeyPolicy(t, "TapStackKeyPolicyLogs")
+
+		// ASSERT
+		template.HasResourceProperties(jsii.String("AWS::KMS::Key"), map[string]interface{}{
+			"KeyPolicy": assertions.Match_ObjectLike(&map[string]interface{}{
+				"Statement": assertions.Match_ArrayWith(&[]interface{}{
+					assertions.Match_ObjectLike(&map[string]interface{}{
+						"Principal": map[string]interface{}{
+							"Service": map[string]interface{}{
+								"Fn::Join": []interface{}{"", []interface{}{"logs.", map[string]interface{}{"Ref": "AWS::Region"}, ".amazonaws.com"}},
+							},
+						},
+						"Condition": map[string]interface{}{
+							"ArnLike": map[string]interface{}{
+								"kms:EncryptionContext:aws:logs:arn": map[string]interface{}{
+									"Fn::Join": []interface{}{"", []interface{}{
+										"arn:", map[string]interface{}{"Ref": "AWS::Partition"},
+										":logs:", map[string]interface{}{"Ref": "AWS::Region"},
+										":", map[string]interface{}{"Ref": "AWS::AccountId"},
+										":log-group:*",
+									}},
+								},
+							},
+						},
+					}),
+				}),
+			}),
+		})
+	})
+
+	t.Run("grants CloudTrail only for the stack's trail", func(t *testing.T) {
+		// ARRANGE
+		stack, template, _ := synthKeyPolicy(t, "TapStackKeyPolicyTrail")
+		key := logicalId(stack, stack.KmsKey.Node().DefaultChild().(awscdk.CfnElement))
+		trailArn := map[string]interface{}{
+			"Fn::Join": []interface{}{"", []interface{}{
+				"arn:", map[string]interface{}{"Ref": "AWS::Partition"},
+				":cloudtrail:", map[string]interface{}{"Ref": "AWS::Region"},
+				":", map[string]interface{}{"Ref": "AWS::AccountId"},
+				":trail/prod-kms-cloudtrail",
+			}},
+		}
+
+		// ASSERT
+		template.HasResourceProperties(jsii.String("AWS::KMS::Key"), map[string]interface{}{
+			"KeyPolicy": assertions.Match_ObjectLike(&map[string]interface{}{
+				"Statement": assertions.Match_ArrayWith(&[]interface{}{
+					assertions.Match_ObjectLike(&map[string]interface{}{
+						"Action":    "kms:GenerateDataKey*",
+						"Principal": map[string]interface{}{"Service": "cloudtrail.amazonaws.com"},
+						"Condition": assertions.Match_ObjectLike(&map[string]interface{}{
+							"StringEquals": map[string]interface{}{"aws:SourceArn": trailArn},
+							"StringLike": map[string]interface{}{
+								"kms:EncryptionContext:aws:cloudtrail:arn": assertions.Match_AnyValue(),
+							},
+						}),
+					}),
+					assertions.Match_ObjectLike(&map[string]interface{}{
+						"Action":    "kms:DescribeKey",
+						"Principal": map[string]interface{}{"Service": "cloudtrail.amazonaws.com"},
+						"Condition": map[string]interface{}{
+							"StringEquals": map[string]interface{}{"aws:SourceArn": trailArn},
+						},
+					}),
+				}),
+			}),
+		})
+		template.HasResourceProperties(jsii.String("AWS::CloudTrail::Trail"), map[string]interface{}{
+			"TrailName": "prod-kms-cloudtrail",
+			"KMSKeyId":  map[string]interface{}{"Fn::GetAtt": []interface{}{key, "Arn"}},
+		})
+	})
+}
@@ -0,0 +1,265 @@
+package lib_test
+
+import (
+	"testing"
+
+	"github.com/TuringGpt/iac-test-automations/lib"
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/assertions"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awskms"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awss3"
+	"github.com/aws/jsii-runtime-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKmsKeyPerDomain(t *testing.T) {
+	defer jsii.Close()
+
+	synthPerDomain := func(id string) (*lib.TapStack, assertions.Template) {
+		app := awscdk.NewApp(nil)
+		stack := lib.NewTapStack(app, jsii.String(id), &lib.TapStackProps{
+			EnvironmentSuffix: jsii.String("kd"),
+			DatabaseEngine:    lib.DatabaseEngineAuroraPostgres,
+			KmsKeyPerDomain:   true,
+		})
+		return stack, assertions.Template_FromStack(stack.Stack, nil)
+	}
+	keyArn := func(stack *lib.TapStack, key awskms.IKey) map[string]interface{} {
+		id := logicalId(stack, key.Node().DefaultChild().(awscdk.CfnElement))
+		return map[string]interface{}{"Fn::GetAtt": []interface{}{id, "Arn"}}
+	}
+	// assertBucketKey checks that every one of buckets is encrypted with key
+	assertBucketKey := func(t *testing.T, stack *lib.TapStack, template assertions.Template, key awskms.IKey, buckets ...awss3.Bucket) {
+		resources := *template.FindResources(jsii.String("AWS::S3::Bucket"), nil)
+		for _, bucket := range buckets {
+			id := logicalId(stack, bucket.Node().DefaultChild().(awscdk.CfnElement))
+			require.Contains(t, resources, id)
+			encryption := (*resources[id])["Properties"].(map[string]interface{})["BucketEncryption"]
+			assert.Equal(t, map[string]interface{}{
+				"ServerSideEncryptionConfiguration": []interface{}{
+					map[string]interface{}{
+						"ServerSideEncryptionByDefault": map[string]interface{}{
+							"KMSMasterKeyID": keyArn(stack, key),
+							"SSEAlgorithm":   "aws:kms",
+						},
+					},
+				},
+			}, encryption, id)
+		}
+	}
+
+	t.Run("keeps a single key by default", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		stack := lib.NewTapStack(app, jsii.String("TapStackSharedKey"), &lib.TapStackProps{
+			EnvironmentSuffix: jsii.String("kd"),
+		})
+
+		// ASSERT
+		assert.False(t, stack.KmsKeys.PerDomain())
+		for _, domain := range lib.KeyDomains {
+			assert.Equal(t, stack.KmsKey, stack.KmsKeys[domain])
+		}
+	})
+
+	t.Run("creates a rotating, aliased key with an output per domain", func(t *testing.T) {
+		// ARRANGE
+		stack, template := synthPerDomain("TapStackKeyPerDomain")
+
+		// ASSERT
+		require.True(t, stack.KmsKeys.PerDomain())
+		assert.Equal(t, stack.KmsKeys[lib.KeyDomainAppData], stack.KmsKey)
+		template.ResourceCountIs(jsii.String("AWS::KMS::Key"), jsii.Number(3))
+		template.AllResourcesProperties(jsii.String("AWS::KMS::Key"), map[string]interface{}{
+			"EnableKeyRotation": true,
+		})
+		for _, alias := range []string{"app-data", "logs", "secrets"} {
+			template.HasResourceProperties(jsii.String("AWS::KMS::Alias"), map[string]interface{}{
+				"AliasName": "alias/prod-kd-" + alias + "-key",
+			})
+		}
+		for _, output := range []string{"AppDataKMSKeyId", "LogsKMSKeyId", "SecretsKMSKeyId"} {
+			template.HasOutput(jsii.String(output), map[string]interface{}{
+				"Export": assertions.Match_AnyValue(),
+			})
+		}
+		template.HasOutput(jsii.String("LogsKMSKeyId"), map[string]interface{}{
+			"Export": map[string]interface{}{"Name": "prod-kd-logs-kms-key-id"},
+		})
+	})
+
+	t.Run("creates the backups key only when it is used", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		stack := lib.NewTapStack(app, jsii.String("TapStackKeyPerDomainBackups"), &lib.TapStackProps{
+			EnvironmentSuffix: jsii.String("kd"),
+			KmsKeyPerDomain:   true,
+		})
+		assert.NotContains(t, stack.KmsKeys, lib.KeyDomainBackups)
+
+		// ACT
+		key := stack.BackupsKmsKey()
+		template := assertions.Template_FromStack(stack.Stack, nil)
+
+		// ASSERT
+		assert.Equal(t, *key.Node().Path(), *stack.BackupsKmsKey().Node().Path())
+		assert.NotEqual(t, *stack.KmsKey.Node().Path(), *key.Node().Path())
+		template.ResourceCountIs(jsii.String("AWS::KMS::Key"), jsii.Number(len(lib.KeyDomains)))
+		template.HasResourceProperties(jsii.String("AWS::KMS::Alias"), map[string]interface{}{
+			"AliasName": "alias/prod-kd-backups-key",
+		})
+		template.HasOutput(jsii.String("BackupsKMSKeyId"), map[string]interface{}{
+			"Export": map[string]interface{}{"Name": "prod-kd-backups-kms-key-id"},
+		})
+		template.HasResourceProperties(jsii.String("AWS::KMS::Key"), map[string]interface{}{
+			"KeyPolicy": assertions.Match_ObjectLike(&map[string]interface{}{
+				"Statement": assertions.Match_ArrayWith(&[]interface{}{
+					assertions.Match_ObjectLike(&map[string]interface{}{
+						"Sid": "KeyUsageThroughIam",
+						"Condition": assertions.Match_ObjectLike(&map[string]interface{}{
+							"StringEquals": assertions.Match_ObjectLike(&map[string]interface{}{
+								"kms:ViaService": []interface{}{map[string]interface{}{
+									"Fn::Join": []interface{}{"", []interface{}{"backup.", map[string]interface{}{"Ref": "AWS::Region"}, ".amazonaws.com"}},
+								}},
+							}),
+						}),
+					}),
+				}),
+			}),
+		})
+	})
+
+	t.Run("returns the shared key for backups by default", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		stack := lib.NewTapStack(app, jsii.String("TapStackSharedKeyBackups"), &lib.TapStackProps{
+			EnvironmentSuffix: jsii.String("kd"),
+		})
+
+		// ACT
+		key := stack.BackupsKmsKey()
+
+		// ASSERT
+		assert.Equal(t, *stack.KmsKey.Node().Path(), *key.Node().Path())
+		assertions.Template_FromStack(stack.Stack, nil).ResourceCountIs(jsii.String("AWS::KMS::Key"), jsii.Number(1))
+	})
+
+	t.Run("encrypts each resource with its domain's key", func(t *testing.T) {
+		// ARRANGE
+		stack, template := synthPerDomain("TapStackKeyPerDomainUse")
+		appData := stack.KmsKeys[lib.KeyDomainAppData]
+		logs := stack.KmsKeys[lib.KeyDomainLogs]
+
+		// ASSERT - app data
+		assertBucketKey(t, stack, template, appData, stack.S3Bucket)
+		template.HasResourceProperties(jsii.String("AWS::RDS::DBCluster"), map[string]interface{}{
+			"KmsKeyId": keyArn(stack, appData),
+		})
+
+		// ASSERT - logs
+		assertBucketKey(t, stack, template, logs, stack.LoggingBucket, stack.Observability.FlowLogsBucket, stack.Analytics.ResultsBucket)
+		template.HasResourceProperties(jsii.String("AWS::CloudTrail::Trail"), map[string]interface{}{
+			"KMSKeyId": keyArn(stack, logs),
+		})
+		template.HasResourceProperties(jsii.String("AWS::Logs::LogGroup"), map[string]interface{}{
+			"LogGroupName": "/aws/ssm/prod-kd-sessions",
+			"KmsKeyId":     keyArn(stack, logs),
+		})
+
+		// ASSERT - secrets
+		template.HasResourceProperties(jsii.String("AWS::SecretsManager::Secret"), map[string]interface{}{
+			"KmsKeyId": keyArn(stack, stack.KmsKeys[lib.KeyDomainSecrets]),
+		})
+	})
+
+	t.Run("encrypts every log group with the logs key", func(t *testing.T) {
+		// ARRANGE - without a database the secret rotates through the stack's own Lambda
+		app := awscdk.NewApp(nil)
+		stack := lib.NewTapStack(app, jsii.String("TapStackKeyPerDomainLogGroups"), &lib.TapStackProps{
+			EnvironmentSuffix: jsii.String("kd"),
+			KmsKeyPerDomain:   true,
+		})
+		template := assertions.Template_FromStack(stack.Stack, nil)
+
+		// ASSERT
+		for _, name := range []string{"/aws/cloudtrail/prod-kd", "/aws/lambda/prod-kd-background-job", "/aws/lambda/prod-kd-secret-rotation"} {
+			template.HasResourceProperties(jsii.String("AWS::Logs::LogGroup"), map[string]interface{}{
+				"LogGroupName": name,
+			})
+		}
+		template.AllResourcesProperties(jsii.String("AWS::Logs::LogGroup"), map[string]interface{}{
+			"KmsKeyId": keyArn(stack, stack.KmsKeys[lib.KeyDomainLogs]),
+		})
+	})
+
+	t.Run("admits service principals only to the logs key", func(t *testing.T) {
+		// ARRANGE
+		stack, template := synthPerDomain("TapStackKeyPerDomainPolicy")
+		logs := logicalId(stack, stack.KmsKeys[lib.KeyDomainLogs].Node().DefaultChild().(awscdk.CfnElement))
+
+		// ASSERT
+		for id, key := range *template.FindResources(jsii.String("AWS::KMS::Key"), nil) {
+			services := 0
+			policy := (*key)["Properties"].(map[string]interface{})["KeyPolicy"].(map[string]interface{})
+			for _, statement := range policy["Statement"].([]interface{}) {
+				principal, _ := statement.(map[string]interface{})["Principal"].(map[string]interface{})
+				if _, ok := principal["Service"]; ok {
+					services++
+				}
+			}
+			if id == logs {
+				assert.NotZero(t, services, id)
+			} else {
+				assert.Zero(t, services, id)
+			}
+		}
+	})
+
+	t.Run("limits each key to its domain's services through IAM", func(t *testing.T) {
+		// ARRANGE
+		stack, template := synthPerDomain("TapStackKeyPerDomainViaService")
+		viaServices := map[lib.KeyDomain][]string{
+			lib.KeyDomainAppData: {"s3", "rds"},
+			lib.KeyDomainLogs:    {"logs", "s3"},
+			lib.KeyDomainSecrets: {"secretsmanager"},
+		}
+		keys := *template.FindResources(jsii.String("AWS::KMS::Key"), nil)
+
+		// ASSERT
+		for domain, services := range viaServices {
+			var want []interface{}
+			for _, service := range services {
+				want = append(want, map[string]interface{}{
+					"Fn::Join": []interface{}{"", []interface{}{service + ".", map[string]interface{}{"Ref": "AWS::Region"}, ".amazonaws.com"}},
+				})
+			}
+			id := logicalId(stack, stack.KmsKeys[domain].Node().DefaultChild().(awscdk.CfnElement))
+			require.Contains(t, keys, id)
+			policy := (*keys[id])["Properties"].(map[string]interface{})["KeyPolicy"].(map[string]interface{})
+			var usage map[string]interface{}
+			for _, statement := range policy["Statement"].([]interface{}) {
+				if statement.(map[string]interface{})["Sid"] == "KeyUsageThroughIam" {
+					usage = statement.(map[string]interface{})
+				}
+			}
+			require.NotNil(t, usage, domain)
+			assert.Equal(t, want, usage["Condition"].(map[string]interface{})["StringEquals"].(map[string]interface{})["kms:ViaService"], domain)
+		}
+	})
+
+	t.Run("rejects per-domain keys with an imported key", func(t *testing.T) {
+		// ARRANGE
+		props := &lib.TapStackProps{
+			ExistingKmsKeyArn: jsii.String(existingKeyArn),
+			KmsKeyPerDomain:   true,
+		}
+
+		// ACT
+		err := props.Validate()
+
+		// ASSERT
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "KmsKeyPerDomain creates its own keys")
+	})
+}
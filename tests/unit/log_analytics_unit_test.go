@@ -0,0 +1,122 @@
+package lib_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/TuringGpt/iac-test-automations/lib"
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/assertions"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awskms"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awss3"
+	"github.com/aws/jsii-runtime-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogAnalytics(t *testing.T) {
+	defer jsii.Close()
+
+	t.Run("registers CloudTrail and CloudFront tables over the logging bucket", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		stack := lib.NewTapStack(app, jsii.String("TapStackLogAnalytics"), &lib.TapStackProps{
+			EnvironmentSuffix: jsii.String("athena"),
+		})
+		template := assertions.Template_FromStack(stack.Stack, nil)
+		bucket := logicalId(stack, stack.LoggingBucket.Node().DefaultChild().(awscdk.CfnElement))
+
+		// ASSERT
+		template.ResourceCountIs(jsii.String("AWS::Glue::Table"), jsii.Number(3))
+		template.HasResourceProperties(jsii.String("AWS::Glue::Table"), map[string]interface{}{
+			"TableInput": assertions.Match_ObjectLike(&map[string]interface{}{
+				"Name": lib.CloudTrailTableName,
+				"PartitionKeys": []interface{}{
+					map[string]interface{}{"Name": "region", "Type": "string"},
+					map[string]interface{}{"Name": "timestamp", "Type": "string"},
+				},
+				"Parameters": assertions.Match_ObjectLike(&map[string]interface{}{
+					"projection.enabled":          "true",
+					"projection.region.values":    strings.Join(lib.CloudTrailRegions, ","),
+					"projection.timestamp.format": "yyyy/MM/dd",
+					"storage.location.template": map[string]interface{}{
+						"Fn::Join": []interface{}{"", []interface{}{
+							"s3://", map[string]interface{}{"Ref": bucket},
+							"/cloudtrail-logs/AWSLogs/", map[string]interface{}{"Ref": "AWS::AccountId"},
+							"/CloudTrail/${region}/${timestamp}/",
+						}},
+					},
+				}),
+				"StorageDescriptor": assertions.Match_ObjectLike(&map[string]interface{}{
+					"InputFormat": "com.amazon.emr.cloudtrail.CloudTrailInputFormat",
+				}),
+			}),
+		})
+		template.HasResourceProperties(jsii.String("AWS::Glue::Table"), map[string]interface{}{
+			"TableInput": assertions.Match_ObjectLike(&map[string]interface{}{
+				"Name": lib.CloudFrontTableName,
+				"StorageDescriptor": assertions.Match_ObjectLike(&map[string]interface{}{
+					"Location": map[string]interface{}{
+						"Fn::Join": []interface{}{"", []interface{}{"s3://", map[string]interface{}{"Ref": bucket}, "/cloudfront-logs/"}},
+					},
+					"Columns": assertions.Match_ArrayWith(&[]interface{}{
+						map[string]interface{}{"Name": "uri", "Type": "string"},
+						map[string]interface{}{"Name": "status", "Type": "int"},
+					}),
+				}),
+			}),
+		})
+		// The tables point at the prefixes the trail and the distribution write to
+		template.HasResourceProperties(jsii.String("AWS::CloudTrail::Trail"), map[string]interface{}{
+			"S3KeyPrefix": "cloudtrail-logs/",
+		})
+		template.HasResourceProperties(jsii.String("AWS::CloudFront::Distribution"), map[string]interface{}{
+			"DistributionConfig": assertions.Match_ObjectLike(&map[string]interface{}{
+				"Logging": assertions.Match_ObjectLike(&map[string]interface{}{"Prefix": "cloudfront-logs/"}),
+			}),
+		})
+	})
+
+	t.Run("saves the named queries in the workgroup", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		stack := lib.NewTapStack(app, jsii.String("TapStackNamedQueries"), &lib.TapStackProps{
+			EnvironmentSuffix: jsii.String("athena"),
+		})
+		template := assertions.Template_FromStack(stack.Stack, nil)
+		workGroup := logicalId(stack, stack.Analytics.WorkGroup)
+		database := logicalId(stack, stack.Analytics.Database)
+
+		// ASSERT
+		require.Len(t, stack.Analytics.NamedQueries, len(lib.DefaultNamedQueries))
+		for _, definition := range lib.DefaultNamedQueries {
+			assert.Contains(t, definition.Query, "FROM "+definition.Table)
+			template.HasResourceProperties(jsii.String("AWS::Athena::NamedQuery"), map[string]interface{}{
+				"Name":        "prod-athena-" + definition.Name,
+				"Database":    map[string]interface{}{"Ref": database},
+				"WorkGroup":   map[string]interface{}{"Ref": workGroup},
+				"QueryString": definition.Query,
+			})
+		}
+	})
+
+	t.Run("skips the flow log table and its queries without flow logs", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		stack := awscdk.NewStack(app, jsii.String("AnalyticsOnly"), nil)
+		analytics := lib.NewLogAnalyticsConstruct(stack, jsii.String("Analytics"), &lib.LogAnalyticsConstructProps{
+			Namer:     lib.NewDefaultNamer("reuse"),
+			KmsKey:    awskms.NewKey(stack, jsii.String("Key"), nil),
+			LogBucket: awss3.NewBucket(stack, jsii.String("Logs"), nil),
+		})
+		template := assertions.Template_FromStack(stack, nil)
+
+		// ASSERT
+		assert.Nil(t, analytics.FlowLogsTable)
+		template.ResourceCountIs(jsii.String("AWS::Glue::Table"), jsii.Number(2))
+		for _, query := range analytics.NamedQueries {
+			assert.NotContains(t, *query.QueryString(), lib.FlowLogsTableName)
+		}
+		assert.Len(t, analytics.NamedQueries, len(lib.DefaultNamedQueries)-1)
+	})
+}
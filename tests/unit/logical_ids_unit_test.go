@@ -0,0 +1,76 @@
+package lib_test
+
+import (
+	"testing"
+
+	"github.com/TuringGpt/iac-test-automations/lib"
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/assertions"
+	"github.com/aws/jsii-runtime-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// logicalId resolves the logical ID of element in the template, which unlike
+// Stack.GetLogicalId includes overrides
+func logicalId(stack awscdk.Stack, element awscdk.CfnElement) string {
+	return stack.Resolve(element.LogicalId()).(string)
+}
+
+func TestLogicalIds(t *testing.T) {
+	defer jsii.Close()
+
+	t.Run("keeps the logical IDs of stateful resources from before the construct split", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		stack := lib.NewTapStack(app, jsii.String("TapStackIds"), &lib.TapStackProps{
+			EnvironmentSuffix: jsii.String("ids"),
+			DatabaseEngine:    lib.DatabaseEngineAuroraPostgres,
+		})
+		template := assertions.Template_FromStack(stack.Stack, nil)
+
+		// ACT
+		resources := (*template.ToJSON())["Resources"].(map[string]interface{})
+
+		// ASSERT - IDs synthesized by the stack before it was split into constructs
+		baseline := map[string]string{
+			"ProdVPC026C7723":                        "AWS::EC2::VPC",
+			"ProdVPCIGW1C16DAD5":                     "AWS::EC2::InternetGateway",
+			"ProdVPCPublicSubnet1SubnetD22BE417":     "AWS::EC2::Subnet",
+			"ProdVPCPublicSubnet2Subnet994DEF76":     "AWS::EC2::Subnet",
+			"ProdVPCPrivateSubnet1SubnetD8AA19A2":    "AWS::EC2::Subnet",
+			"ProdVPCPrivateSubnet2Subnet2E203D10":    "AWS::EC2::Subnet",
+			"ProdVPCIsolatedSubnet1Subnet10336422":   "AWS::EC2::Subnet",
+			"ProdVPCIsolatedSubnet2Subnet6B12DC50":   "AWS::EC2::Subnet",
+			"ProdVPCPublicSubnet1EIP66806F54":        "AWS::EC2::EIP",
+			"ProdKMSKey11E1F124":                     "AWS::KMS::Key",
+			"ProdKMSKeyAliasBD2EB17F":                "AWS::KMS::Alias",
+			"ProdS3Bucket8CD91DA7":                   "AWS::S3::Bucket",
+			"ProdLoggingBucket9881F7EC":              "AWS::S3::Bucket",
+			"VPCFlowLogsBucket108DBF76":              "AWS::S3::Bucket",
+			"ProdDatabase4392ABE9":                   "AWS::RDS::DBCluster",
+			"ProdDatabaseWriterC30C7D64":             "AWS::RDS::DBInstance",
+			"ProdDatabaseReader0F2FC547":             "AWS::RDS::DBInstance",
+			"ProdDBSubnetGroup":                      "AWS::RDS::DBSubnetGroup",
+			"ProdAppSecrets9E29C1E6":                 "AWS::SecretsManager::Secret",
+			"ProdAppSecretsAttachment25E5C92A":       "AWS::SecretsManager::SecretTargetAttachment",
+			"ProdLambdaLogGroupD497F226":             "AWS::Logs::LogGroup",
+			"CloudTrailLogGroup343A29D6":             "AWS::Logs::LogGroup",
+			"ProdCloudTrail159CF4BD":                 "AWS::CloudTrail::Trail",
+			"ProdALBDCBD1F7E":                        "AWS::ElasticLoadBalancingV2::LoadBalancer",
+			"ProdCloudFrontDist51E2EF35":             "AWS::CloudFront::Distribution",
+			"ProdLambdaFunction56143968":             "AWS::Lambda::Function",
+			"ProdAutoScalingGroupASG087F114D":        "AWS::AutoScaling::AutoScalingGroup",
+			"ProdSecurityAlerts14A4DB42":             "AWS::SNS::Topic",
+			"ProdWAF":                                "AWS::WAFv2::WebACL",
+			"ProdLoggingBucketPolicy8877B9B8":        "AWS::S3::BucketPolicy",
+			"ProdVPCPublicSubnet1NATGatewayFF8DEE7F": "AWS::EC2::NatGateway",
+			// Security group rules name their peer by its unique ID
+			"EC2SGfromTapStackIdsALBSGA9596B72802018CBEE": "AWS::EC2::SecurityGroupIngress",
+		}
+		for id, resourceType := range baseline {
+			if assert.Containsf(t, resources, id, "%s changed its logical ID", resourceType) {
+				assert.Equal(t, resourceType, resources[id].(map[string]interface{})["Type"])
+			}
+		}
+	})
+}
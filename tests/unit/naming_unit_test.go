@@ -0,0 +1,153 @@
+package lib_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/TuringGpt/iac-test-automations/lib"
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/assertions"
+	"github.com/aws/jsii-runtime-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNaming(t *testing.T) {
+	defer jsii.Close()
+
+	t.Run("default namer preserves the prod-<suffix> names", func(t *testing.T) {
+		// ARRANGE
+		namer := lib.NewDefaultNamer("dev")
+
+		// ASSERT
+		assert.Equal(t, "prod-dev", namer.Prefix())
+		assert.Equal(t, "prod-dev-lambda-role", namer.Name(lib.ResourceIamRole, "lambda-role"))
+		assert.Equal(t, "prod-dev-alb", namer.Name(lib.ResourceLoadBalancer, "alb"))
+	})
+
+	t.Run("joins organization, application, environment and region", func(t *testing.T) {
+		// ARRANGE
+		namer := &lib.DefaultNamer{
+			Organization: "acme",
+			Application:  "Shop",
+			Environment:  "qa",
+			Region:       "eu-west-1",
+		}
+
+		// ASSERT
+		assert.Equal(t, "acme-Shop-qa-euw1", namer.Prefix())
+		assert.Equal(t, "acme-shop-qa-euw1-app-bucket", namer.Name(lib.ResourceS3Bucket, "app-bucket"))
+		assert.Equal(t, "acme-Shop-qa-euw1-vpc", namer.Name(lib.ResourceGeneric, "vpc"))
+	})
+
+	t.Run("abbreviates region names", func(t *testing.T) {
+		assert.Equal(t, "use1", lib.RegionShortCode("us-east-1"))
+		assert.Equal(t, "apse2", lib.RegionShortCode("ap-southeast-2"))
+		assert.Equal(t, "cac1", lib.RegionShortCode("ca-central-1"))
+	})
+
+	t.Run("truncates names to the service limits", func(t *testing.T) {
+		// ARRANGE
+		namer := &lib.DefaultNamer{
+			Organization: "a-very-long-organization-name",
+			Application:  "customer-facing-application",
+			Environment:  "pr-1234",
+		}
+
+		// ACT
+		role := namer.Name(lib.ResourceIamRole, "secret-rotation-role")
+		lambda := namer.Name(lib.ResourceLambdaFunction, "secret-rotation")
+		bucket := namer.Name(lib.ResourceS3Bucket, "vpc-flow-logs")
+		alb := namer.Name(lib.ResourceLoadBalancer, "alb")
+		tg := namer.Name(lib.ResourceTargetGroup, "tg")
+
+		// ASSERT
+		assert.LessOrEqual(t, len(role), 64)
+		assert.LessOrEqual(t, len(lambda), 64)
+		assert.LessOrEqual(t, len(bucket)+len("-123456789012"), 63)
+		assert.LessOrEqual(t, len(alb), 32)
+		assert.LessOrEqual(t, len(tg), 32)
+		assert.NotEqual(t, alb, tg, "truncated names stay unique")
+		assert.True(t, strings.HasPrefix(role, "a-very-long-organization-name"))
+		assert.Equal(t, "short-name", lib.TruncateName("short-name", 64))
+	})
+
+	for _, tc := range []struct {
+		maxLength int
+		want      string
+	}{
+		{maxLength: 0, want: "a-bcdefghij"},
+		{maxLength: 1, want: "7"},
+		{maxLength: 6, want: "737b06"},
+		{maxLength: 7, want: "737b06"},
+		{maxLength: 8, want: "a-737b06"},
+		{maxLength: 9, want: "a-737b06"},
+		{maxLength: 10, want: "a-b-737b06"},
+		{maxLength: 11, want: "a-bcdefghij"},
+	} {
+		t.Run(fmt.Sprintf("truncates to %d characters", tc.maxLength), func(t *testing.T) {
+			// ACT
+			name := lib.TruncateName("a-bcdefghij", tc.maxLength)
+
+			// ASSERT
+			assert.Equal(t, tc.want, name)
+			assert.False(t, strings.HasPrefix(name, "-"))
+		})
+	}
+
+	t.Run("rejects name parts that are not DNS-safe", func(t *testing.T) {
+		// ARRANGE
+		props := &lib.TapStackProps{
+			Namer: &lib.DefaultNamer{Organization: "acme_corp", Application: "shop-", Environment: "dev"},
+		}
+
+		// ACT
+		err := props.Validate()
+
+		// ASSERT
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `namer: Organization "acme_corp" must be letters, digits and hyphens`)
+		assert.Contains(t, err.Error(), `namer: Application "shop-" must be letters`)
+		assert.NoError(t, (&lib.DefaultNamer{Organization: "acme", Application: "Shop"}).Validate())
+	})
+
+	t.Run("stack uses a custom namer for every resource", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		stack := lib.NewTapStack(app, jsii.String("TapStackNamingTest"), &lib.TapStackProps{
+			StackProps:        &awscdk.StackProps{},
+			EnvironmentSuffix: jsii.String("naming"),
+			Namer: &lib.DefaultNamer{
+				Organization: "acme",
+				Application:  "shop",
+				Environment:  "naming",
+			},
+		})
+		template := assertions.Template_FromStack(stack.Stack, nil)
+
+		// ASSERT
+		template.HasResourceProperties(jsii.String("AWS::IAM::Role"), map[string]interface{}{
+			"RoleName": "acme-shop-naming-lambda-role",
+		})
+		template.HasResourceProperties(jsii.String("AWS::Lambda::Function"), map[string]interface{}{
+			"FunctionName": "acme-shop-naming-background-job",
+		})
+		template.HasResourceProperties(jsii.String("AWS::SecretsManager::Secret"), map[string]interface{}{
+			"Name": "acme-shop-naming/app-secrets",
+		})
+		template.HasResourceProperties(jsii.String("AWS::SSM::Parameter"), map[string]interface{}{
+			"Name": "/acme-shop-naming/app-environment",
+		})
+		template.HasResourceProperties(jsii.String("AWS::WAFv2::WebACL"), map[string]interface{}{
+			"Name": "acme-shop-naming-waf",
+		})
+		template.HasOutput(jsii.String("VPCId"), map[string]interface{}{
+			"Export": map[string]interface{}{"Name": "acme-shop-naming-vpc-id"},
+		})
+		rendered, err := json.Marshal(template.ToJSON())
+		require.NoError(t, err)
+		assert.NotContains(t, string(rendered), "prod-naming")
+	})
+}
@@ -0,0 +1,138 @@
+package lib_test
+
+import (
+	"testing"
+
+	"github.com/TuringGpt/iac-test-automations/lib"
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/assertions"
+	"github.com/aws/jsii-runtime-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// synthNatMode synthesizes a TapStack with the given network configuration
+func synthNatMode(t *testing.T, id string, network *lib.NetworkConfig) (*lib.TapStack, assertions.Template) {
+	t.Helper()
+	app := awscdk.NewApp(nil)
+	stack := lib.NewTapStack(app, jsii.String(id), &lib.TapStackProps{
+		EnvironmentSuffix: jsii.String("nat"),
+		Network:           network,
+	})
+	return stack, assertions.Template_FromStack(stack.Stack, nil)
+}
+
+func TestNatMode(t *testing.T) {
+	defer jsii.Close()
+
+	t.Run("per-az places a NAT gateway in every AZ by default", func(t *testing.T) {
+		// ARRANGE
+		stack, template := synthNatMode(t, "TapStackNatPerAz", nil)
+
+		// ASSERT
+		assert.Equal(t, lib.NatModePerAz, stack.Config.Network.NatMode)
+		template.ResourceCountIs(jsii.String("AWS::EC2::NatGateway"), jsii.Number(2))
+		template.ResourceCountIs(jsii.String("AWS::EC2::Instance"), jsii.Number(1)) // Bastion only
+		assert.Nil(t, stack.Network.NatSecurityGroup)
+	})
+
+	t.Run("single shares one NAT gateway", func(t *testing.T) {
+		// ARRANGE
+		_, template := synthNatMode(t, "TapStackNatSingle", &lib.NetworkConfig{NatMode: lib.NatModeSingle})
+
+		// ASSERT - both private route tables default to the same gateway
+		template.ResourceCountIs(jsii.String("AWS::EC2::NatGateway"), jsii.Number(1))
+		routes := template.FindResources(jsii.String("AWS::EC2::Route"), map[string]interface{}{
+			"Properties": map[string]interface{}{"NatGatewayId": assertions.Match_AnyValue()},
+		})
+		assert.Len(t, *routes, 2)
+	})
+
+	t.Run("instance uses Graviton NAT instances with their own security group", func(t *testing.T) {
+		// ARRANGE
+		stack, template := synthNatMode(t, "TapStackNatInstance", &lib.NetworkConfig{NatMode: lib.NatModeInstance})
+
+		// ASSERT
+		template.ResourceCountIs(jsii.String("AWS::EC2::NatGateway"), jsii.Number(0))
+		natInstances := template.FindResources(jsii.String("AWS::EC2::Instance"), map[string]interface{}{
+			"Properties": map[string]interface{}{
+				"InstanceType":    "t4g.nano",
+				"SourceDestCheck": false,
+			},
+		})
+		assert.Len(t, *natInstances, 2)
+		require.NotNil(t, stack.Network.NatSecurityGroup)
+		template.HasResourceProperties(jsii.String("AWS::EC2::SecurityGroup"), map[string]interface{}{
+			"GroupDescription": "Security Group for NAT instances",
+			"SecurityGroupIngress": []interface{}{
+				assertions.Match_ObjectLike(&map[string]interface{}{
+					"CidrIp":     map[string]interface{}{"Fn::GetAtt": assertions.Match_AnyValue()},
+					"IpProtocol": "-1",
+				}),
+			},
+		})
+		template.HasResourceProperties(jsii.String("AWS::EC2::Route"), map[string]interface{}{
+			"InstanceId": assertions.Match_AnyValue(),
+		})
+	})
+
+	t.Run("none removes internet egress and relies on VPC endpoints", func(t *testing.T) {
+		// ARRANGE
+		stack, template := synthNatMode(t, "TapStackNatNone", &lib.NetworkConfig{
+			NatMode:      lib.NatModeNone,
+			VpcEndpoints: jsii.Bool(true),
+		})
+
+		// ASSERT
+		template.ResourceCountIs(jsii.String("AWS::EC2::NatGateway"), jsii.Number(0))
+		template.ResourceCountIs(jsii.String("AWS::EC2::Instance"), jsii.Number(1)) // Bastion only
+		template.ResourceCountIs(jsii.String("AWS::EC2::VPCEndpoint"), jsii.Number(10))
+		assert.Len(t, *stack.PrivateSubnets, 2)
+		assert.Len(t, *stack.IsolatedSubnets, 2)
+		assert.NotEqual(t, *(*stack.PrivateSubnets)[0].SubnetId(), *(*stack.IsolatedSubnets)[0].SubnetId())
+		// Only the public route tables have a default route (to the internet gateway)
+		defaultRoutes := template.FindResources(jsii.String("AWS::EC2::Route"), map[string]interface{}{
+			"Properties": map[string]interface{}{"DestinationCidrBlock": "0.0.0.0/0"},
+		})
+		assert.Len(t, *defaultRoutes, 2)
+	})
+
+	t.Run("rejects inconsistent NAT settings", func(t *testing.T) {
+		cases := []struct {
+			name    string
+			network *lib.NetworkConfig
+			message string
+		}{
+			{
+				name:    "unknown mode",
+				network: &lib.NetworkConfig{NatMode: "gateway"},
+				message: `unsupported NatMode "gateway"`,
+			},
+			{
+				name:    "single with several gateways",
+				network: &lib.NetworkConfig{NatMode: lib.NatModeSingle, NatGateways: jsii.Number(2)},
+				message: "NatGateways must be unset or 1",
+			},
+			{
+				name:    "x86 NAT instance",
+				network: &lib.NetworkConfig{NatMode: lib.NatModeInstance, NatInstanceType: jsii.String("t3.nano")},
+				message: "Graviton instance type",
+			},
+			{
+				name:    "none without endpoints",
+				network: &lib.NetworkConfig{NatMode: lib.NatModeNone},
+				message: "NatMode none requires VpcEndpoints",
+			},
+		}
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				// ACT
+				err := (&lib.TapStackProps{Network: tc.network}).Validate()
+
+				// ASSERT
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.message)
+			})
+		}
+	})
+}
@@ -0,0 +1,252 @@
+package lib_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/TuringGpt/iac-test-automations/lib"
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/assertions"
+	"github.com/aws/jsii-runtime-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// inboundFromAnywhereCovers reports whether an ingress NACL entry allows the
+// port from 0.0.0.0/0 or ::/0
+func inboundFromAnywhereCovers(entry map[string]interface{}, port float64) bool {
+	if entry["Egress"] == true || entry["RuleAction"] != "allow" {
+		return false
+	}
+	if entry["CidrBlock"] != "0.0.0.0/0" && entry["Ipv6CidrBlock"] != "::/0" {
+		return false
+	}
+	if entry["Protocol"] == float64(-1) {
+		return true
+	}
+	portRange, ok := entry["PortRange"].(map[string]interface{})
+	return ok && portRange["From"].(float64) <= port && port <= portRange["To"].(float64)
+}
+
+func TestNetworkAcls(t *testing.T) {
+	defer jsii.Close()
+
+	t.Run("every tier gets its own network ACL", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		stack := lib.NewTapStack(app, jsii.String("TapStackNacls"), &lib.TapStackProps{
+			EnvironmentSuffix: jsii.String("nacl"),
+		})
+		template := assertions.Template_FromStack(stack.Stack, nil)
+
+		// ASSERT
+		template.ResourceCountIs(jsii.String("AWS::EC2::NetworkAcl"), jsii.Number(3))
+		template.ResourceCountIs(jsii.String("AWS::EC2::SubnetNetworkAclAssociation"), jsii.Number(6))
+		for _, tier := range []lib.SubnetTier{lib.SubnetTierPublic, lib.SubnetTierPrivate, lib.SubnetTierIsolated} {
+			assert.Contains(t, stack.Network.NetworkAcls, tier)
+		}
+		// Return traffic reaches the app tier on ephemeral ports, around RDP
+		privateAcl := stack.Network.NetworkAcls[lib.SubnetTierPrivate].Node().DefaultChild().(awscdk.CfnElement)
+		for _, portRange := range []map[string]interface{}{
+			{"From": lib.EphemeralPortStart, "To": lib.RdpPort - 1},
+			{"From": lib.RdpPort + 1, "To": lib.EphemeralPortEnd},
+		} {
+			template.HasResourceProperties(jsii.String("AWS::EC2::NetworkAclEntry"), map[string]interface{}{
+				"NetworkAclId": map[string]interface{}{"Ref": logicalId(stack, privateAcl)},
+				"CidrBlock":    "0.0.0.0/0",
+				"Egress":       false,
+				"Protocol":     6,
+				"PortRange":    portRange,
+			})
+		}
+	})
+
+	t.Run("app and data tiers allow no inbound SSH or RDP from anywhere", func(t *testing.T) {
+		for _, dualStack := range []bool{false, true} {
+			t.Run(fmt.Sprintf("dualStack=%v", dualStack), func(t *testing.T) {
+				// ARRANGE
+				app := awscdk.NewApp(nil)
+				stack := lib.NewTapStack(app, jsii.String(fmt.Sprintf("TapStackNaclsAdmin%v", dualStack)), &lib.TapStackProps{
+					EnvironmentSuffix: jsii.String("nacl"),
+					Network:           &lib.NetworkConfig{DualStack: jsii.Bool(dualStack)},
+				})
+				template := assertions.Template_FromStack(stack.Stack, nil)
+
+				// ACT
+				entries := template.FindResources(jsii.String("AWS::EC2::NetworkAclEntry"), nil)
+
+				// ASSERT
+				checked := 0
+				for _, tier := range []lib.SubnetTier{lib.SubnetTierPrivate, lib.SubnetTierIsolated} {
+					acl := stack.Network.NetworkAcls[tier]
+					require.NotNil(t, acl)
+					aclLogicalId := logicalId(stack, acl.Node().DefaultChild().(awscdk.CfnElement))
+					for id, resource := range *entries {
+						entry := (*resource)["Properties"].(map[string]interface{})
+						if entry["NetworkAclId"].(map[string]interface{})["Ref"] != aclLogicalId {
+							continue
+						}
+						checked++
+						for _, port := range []float64{22, 3389} {
+							assert.Falsef(t, inboundFromAnywhereCovers(entry, port), "%s tier entry %s allows port %v from anywhere", tier, id, port)
+						}
+					}
+				}
+				assert.Positive(t, checked)
+			})
+		}
+	})
+
+	t.Run("mirror every rule for IPv6 in dual-stack mode", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		stack := lib.NewTapStack(app, jsii.String("TapStackNaclsIpv6"), &lib.TapStackProps{
+			EnvironmentSuffix: jsii.String("nacl"),
+			Network:           &lib.NetworkConfig{DualStack: jsii.Bool(true)},
+		})
+		template := assertions.Template_FromStack(stack.Stack, nil)
+
+		// ASSERT
+		// Transit Gateway, bastion and HTTPS rules only exist with a Transit
+		// Gateway attached, a bastion allowlist or an HTTPS listener
+		rules := 0
+		for _, tierRules := range lib.DefaultNetworkAclRules {
+			for _, rule := range tierRules {
+				if rule.Peer != lib.NaclPeerTransitGateway && rule.Peer != lib.NaclPeerBastionAllowlist && !rule.Https {
+					rules++
+				}
+			}
+		}
+		template.ResourceCountIs(jsii.String("AWS::EC2::NetworkAclEntry"), jsii.Number(float64(2*rules)))
+		template.HasResourceProperties(jsii.String("AWS::EC2::NetworkAclEntry"), map[string]interface{}{
+			"Ipv6CidrBlock": "::/0",
+			"RuleNumber":    101,
+		})
+	})
+
+	t.Run("public tier and ALB accept HTTPS only with an HTTPS listener", func(t *testing.T) {
+		for _, https := range []bool{false, true} {
+			t.Run(fmt.Sprintf("https=%v", https), func(t *testing.T) {
+				// ARRANGE
+				app := awscdk.NewApp(nil)
+				props := &lib.TapStackProps{
+					EnvironmentSuffix: jsii.String("nacl"),
+					Network:           &lib.NetworkConfig{DualStack: jsii.Bool(true)},
+				}
+				if https {
+					props.CertificateArn = jsii.String("arn:aws:acm:us-east-1:123456789012:certificate/test")
+				}
+				stack := lib.NewTapStack(app, jsii.String(fmt.Sprintf("TapStackNaclsHttps%v", https)), props)
+				template := assertions.Template_FromStack(stack.Stack, nil)
+
+				// ACT
+				naclEntries := 0
+				for _, resource := range *template.FindResources(jsii.String("AWS::EC2::NetworkAclEntry"), nil) {
+					if inboundFromAnywhereCovers((*resource)["Properties"].(map[string]interface{}), 443) {
+						naclEntries++
+					}
+				}
+				albSG := logicalId(stack, stack.Security.SecurityGroups["alb"].Node().DefaultChild().(awscdk.CfnElement))
+				ingress := (*template.ToJSON())["Resources"].(map[string]interface{})[albSG].(map[string]interface{})["Properties"].(map[string]interface{})["SecurityGroupIngress"].([]interface{})
+				sgRules := 0
+				for _, rule := range ingress {
+					if rule.(map[string]interface{})["FromPort"] == float64(443) {
+						sgRules++
+					}
+				}
+
+				// ASSERT - one IPv4 and one IPv6 rule each, or none
+				expected := 0
+				if https {
+					expected = 2
+				}
+				assert.Equal(t, expected, naclEntries)
+				assert.Equal(t, expected, sgRules)
+			})
+		}
+	})
+
+	t.Run("public tier allows no inbound SSH outside cidr-allowlist mode", func(t *testing.T) {
+		for _, mode := range []lib.BastionMode{lib.BastionModeSsm, lib.BastionModeNone} {
+			t.Run(string(mode), func(t *testing.T) {
+				// ARRANGE
+				app := awscdk.NewApp(nil)
+				stack := lib.NewTapStack(app, jsii.String("TapStackNaclsSsh"+strings.ReplaceAll(string(mode), "-", "")), &lib.TapStackProps{
+					EnvironmentSuffix: jsii.String("nacl"),
+					Network:           &lib.NetworkConfig{DualStack: jsii.Bool(true)},
+					Compute:           &lib.ComputeConfig{BastionMode: mode},
+				})
+				template := assertions.Template_FromStack(stack.Stack, nil)
+				publicAcl := logicalId(stack, stack.Network.NetworkAcls[lib.SubnetTierPublic].Node().DefaultChild().(awscdk.CfnElement))
+
+				// ASSERT
+				for id, resource := range *template.FindResources(jsii.String("AWS::EC2::NetworkAclEntry"), nil) {
+					entry := (*resource)["Properties"].(map[string]interface{})
+					if entry["NetworkAclId"].(map[string]interface{})["Ref"] != publicAcl || entry["Egress"] == true {
+						continue
+					}
+					portRange, ok := entry["PortRange"].(map[string]interface{})
+					assert.Falsef(t, ok && portRange["From"] == float64(22), "public tier entry %s allows SSH", id)
+				}
+			})
+		}
+	})
+
+	t.Run("public tier allows SSH only from the bastion allowlist", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		stack := lib.NewTapStack(app, jsii.String("TapStackNaclsSshAllowlist"), &lib.TapStackProps{
+			EnvironmentSuffix: jsii.String("nacl"),
+			Compute: &lib.ComputeConfig{
+				BastionMode:         lib.BastionModeCidrAllowlist,
+				BastionAllowedCidrs: []string{"203.0.113.0/24", "2001:db8::/32"},
+			},
+		})
+		template := assertions.Template_FromStack(stack.Stack, nil)
+		publicAcl := map[string]interface{}{"Ref": logicalId(stack, stack.Network.NetworkAcls[lib.SubnetTierPublic].Node().DefaultChild().(awscdk.CfnElement))}
+
+		// ASSERT
+		template.ResourcePropertiesCountIs(jsii.String("AWS::EC2::NetworkAclEntry"), map[string]interface{}{
+			"PortRange": map[string]interface{}{"From": 22, "To": 22},
+		}, jsii.Number(2))
+		template.HasResourceProperties(jsii.String("AWS::EC2::NetworkAclEntry"), map[string]interface{}{
+			"NetworkAclId": publicAcl,
+			"CidrBlock":    "203.0.113.0/24",
+			"RuleNumber":   300,
+			"PortRange":    map[string]interface{}{"From": 22, "To": 22},
+		})
+		template.HasResourceProperties(jsii.String("AWS::EC2::NetworkAclEntry"), map[string]interface{}{
+			"NetworkAclId":  publicAcl,
+			"Ipv6CidrBlock": "2001:db8::/32",
+			"RuleNumber":    301,
+			"PortRange":     map[string]interface{}{"From": 22, "To": 22},
+		})
+	})
+
+	t.Run("rule numbers are unique per tier and direction", func(t *testing.T) {
+		for tier, rules := range lib.DefaultNetworkAclRules {
+			seen := map[string]bool{}
+			for _, rule := range rules {
+				key := fmt.Sprintf("%s/%v", rule.Direction, rule.Number)
+				assert.Falsef(t, seen[key], "%s tier reuses rule %s", tier, key)
+				assert.Zerof(t, int(rule.Number)%10, "%s tier rule %v is not a multiple of 10", tier, rule.Number)
+				seen[key] = true
+			}
+		}
+	})
+
+	t.Run("can be disabled", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		stack := lib.NewTapStack(app, jsii.String("TapStackNoNacls"), &lib.TapStackProps{
+			EnvironmentSuffix: jsii.String("nacl"),
+			Network:           &lib.NetworkConfig{NetworkAcls: jsii.Bool(false)},
+		})
+		template := assertions.Template_FromStack(stack.Stack, nil)
+
+		// ASSERT
+		template.ResourceCountIs(jsii.String("AWS::EC2::NetworkAcl"), jsii.Number(0))
+		assert.Empty(t, stack.Network.NetworkAcls)
+	})
+}
@@ -0,0 +1,189 @@
+package lib_test
+
+import (
+	"testing"
+
+	"github.com/TuringGpt/iac-test-automations/lib"
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/assertions"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsec2"
+	"github.com/aws/jsii-runtime-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetworkFirewall(t *testing.T) {
+	defer jsii.Close()
+
+	t.Run("is disabled by default", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		stack := lib.NewTapStack(app, jsii.String("TapStackNoFirewall"), &lib.TapStackProps{
+			EnvironmentSuffix: jsii.String("nfw"),
+		})
+		template := assertions.Template_FromStack(stack.Stack, nil)
+
+		// ASSERT
+		assert.Nil(t, stack.NetworkFirewall)
+		assert.Nil(t, stack.Network.FirewallSubnets)
+		template.ResourceCountIs(jsii.String("AWS::NetworkFirewall::Firewall"), jsii.Number(0))
+		template.ResourceCountIs(jsii.String("AWS::EC2::Subnet"), jsii.Number(6))
+		assert.Empty(t, *template.FindOutputs(jsii.String("NetworkFirewallPolicyArn"), nil))
+	})
+
+	t.Run("routes private egress through a firewall endpoint in each AZ", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		stack := lib.NewTapStack(app, jsii.String("TapStackFirewall"), &lib.TapStackProps{
+			EnvironmentSuffix: jsii.String("nfw"),
+			Network: &lib.NetworkConfig{
+				NetworkFirewall:               jsii.Bool(true),
+				NetworkFirewallAllowedDomains: []string{"mirror.example.com"},
+			},
+		})
+		template := assertions.Template_FromStack(stack.Stack, nil)
+
+		// ASSERT
+		require.NotNil(t, stack.NetworkFirewall)
+		require.Len(t, stack.NetworkFirewall.Firewalls, 2)
+		template.ResourceCountIs(jsii.String("AWS::NetworkFirewall::Firewall"), jsii.Number(2))
+		template.HasResourceProperties(jsii.String("AWS::NetworkFirewall::RuleGroup"), map[string]interface{}{
+			"Type": "STATEFUL",
+			"RuleGroup": assertions.Match_ObjectLike(&map[string]interface{}{
+				"RulesSource": map[string]interface{}{
+					"RulesSourceList": map[string]interface{}{
+						"GeneratedRulesType": "ALLOWLIST",
+						"TargetTypes":        []interface{}{"TLS_SNI", "HTTP_HOST"},
+						"Targets":            []interface{}{".amazonaws.com", "mirror.example.com"},
+					},
+				},
+			}),
+		})
+		template.HasResourceProperties(jsii.String("AWS::NetworkFirewall::FirewallPolicy"), map[string]interface{}{
+			"FirewallPolicy": assertions.Match_ObjectLike(&map[string]interface{}{
+				"StatefulDefaultActions": []interface{}{"aws:drop_established", "aws:alert_established"},
+			}),
+		})
+		template.HasOutput(jsii.String("NetworkFirewallPolicyArn"), map[string]interface{}{})
+
+		for i, private := range *stack.PrivateSubnets {
+			firewall := logicalId(stack, stack.NetworkFirewall.Firewalls[i])
+			firewallSubnet := (*stack.Network.FirewallSubnets)[i]
+			endpoint := map[string]interface{}{
+				"Fn::Select": []interface{}{1, map[string]interface{}{
+					"Fn::Split": []interface{}{":", map[string]interface{}{
+						"Fn::Select": []interface{}{0, map[string]interface{}{"Fn::GetAtt": []interface{}{firewall, "EndpointIds"}}},
+					}},
+				}},
+			}
+
+			template.HasResourceProperties(jsii.String("AWS::NetworkFirewall::Firewall"), map[string]interface{}{
+				"SubnetMappings": []interface{}{map[string]interface{}{
+					"SubnetId": map[string]interface{}{"Ref": logicalId(stack, firewallSubnet.Node().DefaultChild().(awscdk.CfnElement))},
+				}},
+			})
+			// The private default route goes to the firewall instead of the NAT gateway
+			template.HasResourceProperties(jsii.String("AWS::EC2::Route"), map[string]interface{}{
+				"RouteTableId":  stack.Resolve(private.RouteTable().RouteTableId()),
+				"VpcEndpointId": endpoint,
+				"NatGatewayId":  assertions.Match_Absent(),
+			})
+			template.HasResourceProperties(jsii.String("AWS::EC2::Route"), map[string]interface{}{
+				"RouteTableId":         stack.Resolve(firewallSubnet.RouteTable().RouteTableId()),
+				"DestinationCidrBlock": "0.0.0.0/0",
+				"NatGatewayId":         assertions.Match_AnyValue(),
+			})
+			// Return traffic from every NAT subnet comes back through the same endpoint
+			template.ResourcePropertiesCountIs(jsii.String("AWS::EC2::Route"), map[string]interface{}{
+				"DestinationCidrBlock": *private.Ipv4CidrBlock(),
+				"VpcEndpointId":        endpoint,
+			}, jsii.Number(float64(len(*stack.Network.NatSubnets))))
+		}
+	})
+
+	t.Run("keeps the NAT gateways out of the ALB subnets and the tier CIDRs unchanged", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		stack := lib.NewTapStack(app, jsii.String("TapStackFirewallSubnets"), &lib.TapStackProps{
+			EnvironmentSuffix: jsii.String("nfw"),
+			Network:           &lib.NetworkConfig{NetworkFirewall: jsii.Bool(true)},
+		})
+		template := assertions.Template_FromStack(stack.Stack, nil)
+
+		// ACT
+		natGateways := template.FindResources(jsii.String("AWS::EC2::NatGateway"), nil)
+
+		// ASSERT
+		natSubnetIds := map[string]bool{}
+		for _, subnet := range *stack.Network.NatSubnets {
+			natSubnetIds[logicalId(stack, subnet.Node().DefaultChild().(awscdk.CfnElement))] = true
+		}
+		require.Len(t, *natGateways, 2)
+		for _, natGateway := range *natGateways {
+			subnet := (*natGateway)["Properties"].(map[string]interface{})["SubnetId"].(map[string]interface{})["Ref"]
+			assert.True(t, natSubnetIds[subnet.(string)], "NAT gateway in %v", subnet)
+		}
+		assert.Equal(t, []string{"10.0.0.0/24", "10.0.1.0/24"}, subnetCidrs(stack.PublicSubnets))
+		assert.Equal(t, []string{"10.0.2.0/24", "10.0.3.0/24"}, subnetCidrs(stack.PrivateSubnets))
+		assert.Equal(t, []string{"10.0.4.0/24", "10.0.5.0/24"}, subnetCidrs(stack.IsolatedSubnets))
+		assert.Equal(t, []string{"10.0.6.0/28", "10.0.6.16/28"}, subnetCidrs(stack.Network.FirewallSubnets))
+		assert.Equal(t, []string{"10.0.6.32/28", "10.0.6.48/28"}, subnetCidrs(stack.Network.NatSubnets))
+	})
+
+	t.Run("rejects unsupported firewall settings", func(t *testing.T) {
+		cases := []struct {
+			name    string
+			network *lib.NetworkConfig
+			message string
+		}{
+			{
+				name:    "NAT instances",
+				network: &lib.NetworkConfig{NetworkFirewall: jsii.Bool(true), NatMode: lib.NatModeInstance},
+				message: "NatMode must be per-az or single",
+			},
+			{
+				name:    "dual-stack",
+				network: &lib.NetworkConfig{NetworkFirewall: jsii.Bool(true), DualStack: jsii.Bool(true)},
+				message: "does not inspect IPv6 egress",
+			},
+			{
+				name:    "invalid domain",
+				network: &lib.NetworkConfig{NetworkFirewall: jsii.Bool(true), NetworkFirewallAllowedDomains: []string{"*.example.com"}},
+				message: `NetworkFirewallAllowedDomains entry "*.example.com"`,
+			},
+			{
+				name:    "domains without the firewall",
+				network: &lib.NetworkConfig{NetworkFirewallAllowedDomains: []string{"mirror.example.com"}},
+				message: "NetworkFirewallAllowedDomains requires NetworkFirewall",
+			},
+			{
+				name: "no room for the firewall tiers",
+				network: &lib.NetworkConfig{
+					NetworkFirewall: jsii.Bool(true),
+					VpcCidr:         jsii.String("10.0.0.0/25"),
+					SubnetCidrMask:  jsii.Number(28),
+				},
+				message: "has room for 2 /28 subnets after the other tiers but NetworkFirewall needs 4",
+			},
+		}
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				// ACT
+				err := (&lib.TapStackProps{Network: tc.network}).Validate()
+
+				// ASSERT
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.message)
+			})
+		}
+	})
+}
+
+// subnetCidrs returns the IPv4 CIDR blocks of the subnets in order
+func subnetCidrs(subnets *[]awsec2.ISubnet) []string {
+	var cidrs []string
+	for _, subnet := range *subnets {
+		cidrs = append(cidrs, *subnet.Ipv4CidrBlock())
+	}
+	return cidrs
+}
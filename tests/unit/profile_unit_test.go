@@ -0,0 +1,153 @@
+package lib_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/TuringGpt/iac-test-automations/lib"
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/assertions"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslogs"
+	"github.com/aws/jsii-runtime-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const profileDir = "../../config"
+
+func TestEnvironmentProfiles(t *testing.T) {
+	defer jsii.Close()
+
+	t.Run("shipped profiles are valid", func(t *testing.T) {
+		for _, name := range []string{"dev", "staging", "prod"} {
+			profile, err := lib.LoadProfile(profileDir, name)
+			require.NoError(t, err, name)
+
+			props := &lib.TapStackProps{}
+			props.ApplyProfile(profile)
+			assert.NoError(t, props.Validate(), name)
+		}
+	})
+
+	t.Run("dev uses a single NAT gateway and DESTROY", func(t *testing.T) {
+		profile, err := lib.LoadProfile(profileDir, "dev")
+		require.NoError(t, err)
+
+		assert.Equal(t, float64(1), *profile.Network.NatGateways)
+		assert.Equal(t, awscdk.RemovalPolicy_DESTROY, profile.RemovalPolicy)
+	})
+
+	t.Run("prod uses three AZs and RETAIN", func(t *testing.T) {
+		profile, err := lib.LoadProfile(profileDir, "prod")
+		require.NoError(t, err)
+
+		assert.Equal(t, float64(3), *profile.Network.MaxAzs)
+		assert.Equal(t, awscdk.RemovalPolicy_RETAIN, profile.RemovalPolicy)
+	})
+
+	t.Run("merges defaults, profile and explicit props in that order", func(t *testing.T) {
+		// ARRANGE
+		profile, err := lib.ParseProfile("inline", []byte(`{
+			"compute": {"instanceType": "t3.large", "maxCapacity": 5},
+			"logging": {"retention": "TWO_WEEKS"}
+		}`))
+		require.NoError(t, err)
+		props := &lib.TapStackProps{
+			Compute: &lib.ComputeConfig{MaxCapacity: jsii.Number(4)},
+		}
+
+		// ACT
+		props.ApplyProfile(profile)
+		config, err := props.Config()
+
+		// ASSERT
+		require.NoError(t, err)
+		assert.Equal(t, "t3.large", *config.Compute.InstanceType) // from profile
+		assert.Equal(t, float64(4), *config.Compute.MaxCapacity)  // explicit props win
+		assert.Equal(t, float64(1), *config.Compute.MinCapacity)  // default
+		assert.Equal(t, awslogs.RetentionDays_TWO_WEEKS, config.Logging.Retention)
+		assert.Equal(t, "10.0.0.0/16", *config.Network.VpcCidr)
+	})
+
+	t.Run("rejects unknown keys", func(t *testing.T) {
+		_, err := lib.ParseProfile("typo.json", []byte(`{"network": {"natGateway": 1}}`))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `typo.json`)
+		assert.Contains(t, err.Error(), `unknown field "natGateway"`)
+
+		_, err = lib.ParseProfile("top.json", []byte(`{"databse": {}}`))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `unknown field "databse"`)
+	})
+
+	t.Run("rejects trailing data", func(t *testing.T) {
+		_, err := lib.ParseProfile("trailing.json", []byte(`{} {}`))
+		assert.Error(t, err)
+	})
+
+	t.Run("returns an empty profile when the file does not exist", func(t *testing.T) {
+		profile, err := lib.LoadProfile(t.TempDir(), "pr123")
+		require.NoError(t, err)
+		assert.Equal(t, lib.StackConfig{}, profile)
+	})
+
+	t.Run("reports the file of an invalid profile", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "qa.json"), []byte(`{"network": [`), 0o600))
+
+		_, err := lib.LoadProfile(dir, "qa")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "qa.json")
+	})
+
+	t.Run("rejects profile names that escape the directory", func(t *testing.T) {
+		_, err := lib.LoadProfile(profileDir, "../secrets")
+		assert.Error(t, err)
+	})
+
+	t.Run("synthesizes the dev profile with a single NAT gateway", func(t *testing.T) {
+		// ARRANGE
+		profile, err := lib.LoadProfile(profileDir, "dev")
+		require.NoError(t, err)
+		props := &lib.TapStackProps{
+			StackProps:        &awscdk.StackProps{},
+			EnvironmentSuffix: jsii.String("dev"),
+		}
+		props.ApplyProfile(profile)
+
+		// ACT
+		stack := lib.NewTapStack(awscdk.NewApp(nil), jsii.String("DevProfileTest"), props)
+		template := assertions.Template_FromStack(stack.Stack, nil)
+
+		// ASSERT
+		template.ResourceCountIs(jsii.String("AWS::EC2::NatGateway"), jsii.Number(1))
+		template.HasResource(jsii.String("AWS::S3::Bucket"), map[string]interface{}{
+			"DeletionPolicy": "Delete",
+		})
+	})
+
+	t.Run("synthesizes the prod profile with retained data", func(t *testing.T) {
+		// ARRANGE
+		profile, err := lib.LoadProfile(profileDir, "prod")
+		require.NoError(t, err)
+		props := &lib.TapStackProps{
+			StackProps:        &awscdk.StackProps{},
+			EnvironmentSuffix: jsii.String("prod"),
+		}
+		props.ApplyProfile(profile)
+
+		// ACT
+		stack := lib.NewTapStack(awscdk.NewApp(nil), jsii.String("ProdProfileTest"), props)
+		template := assertions.Template_FromStack(stack.Stack, nil)
+
+		// ASSERT - Stateful resources survive stack deletion
+		template.AllResources(jsii.String("AWS::S3::Bucket"), map[string]interface{}{
+			"DeletionPolicy": "Retain",
+		})
+		template.AllResources(jsii.String("AWS::KMS::Key"), map[string]interface{}{
+			"DeletionPolicy": "Retain",
+		})
+		template.ResourceCountIs(jsii.String("Custom::S3AutoDeleteObjects"), jsii.Number(0))
+	})
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/aws/aws-cdk-go/awscdk/v2/assertions"
 	"github.com/aws/jsii-runtime-go"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestTapStack(t *testing.T) {
@@ -32,14 +33,14 @@ func TestTapStack(t *testing.T) {
 		})
 
 		// ASSERT - S3 Buckets
-		template.ResourceCountIs(jsii.String("AWS::S3::Bucket"), jsii.Number(3)) // App bucket, Logging bucket, VPC Flow logs bucket
+		template.ResourceCountIs(jsii.String("AWS::S3::Bucket"), jsii.Number(4)) // App bucket, Logging bucket, VPC Flow logs bucket, Athena results bucket
 
 		// ASSERT - KMS Key
 		template.ResourceCountIs(jsii.String("AWS::KMS::Key"), jsii.Number(1))
 		template.ResourceCountIs(jsii.String("AWS::KMS::Alias"), jsii.Number(1))
 
-		// ASSERT - Lambda Function (There might be more than 1 due to bastion host)
-		template.ResourceCountIs(jsii.String("AWS::Lambda::Function"), jsii.Number(2))
+		// ASSERT - Lambda Functions (background job, secret rotation, auto-delete custom resource)
+		template.ResourceCountIs(jsii.String("AWS::Lambda::Function"), jsii.Number(3))
 		template.HasResourceProperties(jsii.String("AWS::Lambda::Function"), map[string]interface{}{
 			"Runtime":    "python3.9",
 			"Handler":    "index.lambda_handler",
@@ -48,7 +49,7 @@ func TestTapStack(t *testing.T) {
 		})
 
 		// ASSERT - Security Groups
-		template.ResourceCountIs(jsii.String("AWS::EC2::SecurityGroup"), jsii.Number(3)) // Lambda, EC2, Bastion
+		template.ResourceCountIs(jsii.String("AWS::EC2::SecurityGroup"), jsii.Number(4)) // Lambda, EC2, ALB, Bastion
 
 		// ASSERT - Auto Scaling Group
 		template.ResourceCountIs(jsii.String("AWS::AutoScaling::AutoScalingGroup"), jsii.Number(1))
@@ -73,7 +74,7 @@ func TestTapStack(t *testing.T) {
 		template.ResourceCountIs(jsii.String("AWS::SSM::Parameter"), jsii.Number(4))
 
 		// ASSERT - CloudWatch Log Groups
-		template.ResourceCountIs(jsii.String("AWS::Logs::LogGroup"), jsii.Number(2)) // Lambda and CloudTrail
+		template.ResourceCountIs(jsii.String("AWS::Logs::LogGroup"), jsii.Number(4)) // Lambda, secret rotation, CloudTrail and Session Manager
 
 		// ASSERT - Stack properties
 		assert.NotNil(t, stack)
@@ -89,8 +90,8 @@ func TestTapStack(t *testing.T) {
 		})
 		template := assertions.Template_FromStack(stack.Stack, nil)
 
-		// ASSERT - Subnets (2 public + 2 private = 4 total)
-		template.ResourceCountIs(jsii.String("AWS::EC2::Subnet"), jsii.Number(4))
+		// ASSERT - Subnets (2 public + 2 private + 2 isolated = 6 total)
+		template.ResourceCountIs(jsii.String("AWS::EC2::Subnet"), jsii.Number(6))
 		template.ResourceCountIs(jsii.String("AWS::EC2::InternetGateway"), jsii.Number(1))
 		template.ResourceCountIs(jsii.String("AWS::EC2::NatGateway"), jsii.Number(2)) // One per AZ
 
@@ -170,7 +171,7 @@ func TestTapStack(t *testing.T) {
 
 		// ASSERT - At least one S3 bucket policy exists (for CloudTrail access)
 		// The exact policy structure is managed by CDK, but we verify policies exist
-		template.ResourcePropertiesCountIs(jsii.String("AWS::S3::BucketPolicy"), map[string]interface{}{}, jsii.Number(3))
+		template.ResourcePropertiesCountIs(jsii.String("AWS::S3::BucketPolicy"), map[string]interface{}{}, jsii.Number(4))
 	})
 
 	t.Run("creates Lambda function with least privilege IAM role", func(t *testing.T) {
@@ -191,8 +192,8 @@ func TestTapStack(t *testing.T) {
 			"Architectures": []interface{}{"x86_64"},
 		})
 
-		// ASSERT - IAM Roles (Lambda, EC2, Bastion + auto-delete custom resource)
-		template.ResourceCountIs(jsii.String("AWS::IAM::Role"), jsii.Number(5))
+		// ASSERT - IAM Roles (Lambda, secret rotation, EC2, Bastion + auto-delete custom resource)
+		template.ResourceCountIs(jsii.String("AWS::IAM::Role"), jsii.Number(6))
 	})
 
 	t.Run("creates EC2 resources in private subnets only", func(t *testing.T) {
@@ -216,6 +217,220 @@ func TestTapStack(t *testing.T) {
 		template.ResourceCountIs(jsii.String("AWS::EC2::LaunchTemplate"), jsii.Number(1))
 	})
 
+	t.Run("creates internet-facing ALB in front of the Auto Scaling Group", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		stack := lib.NewTapStack(app, jsii.String("ALBTest"), &lib.TapStackProps{
+			StackProps:        &awscdk.StackProps{},
+			EnvironmentSuffix: jsii.String("alb-test"),
+		})
+		template := assertions.Template_FromStack(stack.Stack, nil)
+
+		// ASSERT - Load balancer is internet-facing
+		assert.NotNil(t, stack.LoadBalancer)
+		template.ResourceCountIs(jsii.String("AWS::ElasticLoadBalancingV2::LoadBalancer"), jsii.Number(1))
+		template.HasResourceProperties(jsii.String("AWS::ElasticLoadBalancingV2::LoadBalancer"), map[string]interface{}{
+			"Scheme": "internet-facing",
+			"Type":   "application",
+		})
+
+		// ASSERT - Target group health checks the application instances
+		template.HasResourceProperties(jsii.String("AWS::ElasticLoadBalancingV2::TargetGroup"), map[string]interface{}{
+			"Port":            80,
+			"Protocol":        "HTTP",
+			"HealthCheckPath": "/health",
+		})
+		template.HasResourceProperties(jsii.String("AWS::AutoScaling::AutoScalingGroup"), map[string]interface{}{
+			"TargetGroupARNs": assertions.Match_AnyValue(),
+		})
+
+		// ASSERT - Without a certificate only the HTTP listener exists
+		template.ResourceCountIs(jsii.String("AWS::ElasticLoadBalancingV2::Listener"), jsii.Number(1))
+
+		// ASSERT - EC2 instances only accept HTTP from the ALB security group
+		template.HasResourceProperties(jsii.String("AWS::EC2::SecurityGroupIngress"), map[string]interface{}{
+			"IpProtocol":            "tcp",
+			"FromPort":              80,
+			"ToPort":                80,
+			"SourceSecurityGroupId": assertions.Match_AnyValue(),
+		})
+		template.HasOutput(jsii.String("LoadBalancerDNSName"), map[string]interface{}{})
+	})
+
+	t.Run("creates HTTPS listener and HTTP redirect when a certificate is provided", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		stack := lib.NewTapStack(app, jsii.String("ALBHttpsTest"), &lib.TapStackProps{
+			StackProps:        &awscdk.StackProps{},
+			EnvironmentSuffix: jsii.String("alb-https-test"),
+			CertificateArn:    jsii.String("arn:aws:acm:us-east-1:123456789012:certificate/test"),
+		})
+		template := assertions.Template_FromStack(stack.Stack, nil)
+
+		// ASSERT - HTTPS listener with certificate
+		template.ResourceCountIs(jsii.String("AWS::ElasticLoadBalancingV2::Listener"), jsii.Number(2))
+		template.HasResourceProperties(jsii.String("AWS::ElasticLoadBalancingV2::Listener"), map[string]interface{}{
+			"Port":     443,
+			"Protocol": "HTTPS",
+			"Certificates": []interface{}{
+				map[string]interface{}{
+					"CertificateArn": "arn:aws:acm:us-east-1:123456789012:certificate/test",
+				},
+			},
+		})
+
+		// ASSERT - HTTP listener redirects to HTTPS
+		template.HasResourceProperties(jsii.String("AWS::ElasticLoadBalancingV2::Listener"), map[string]interface{}{
+			"Port":     80,
+			"Protocol": "HTTP",
+			"DefaultActions": []interface{}{
+				map[string]interface{}{
+					"Type": "redirect",
+					"RedirectConfig": map[string]interface{}{
+						"Protocol":   "HTTPS",
+						"Port":       "443",
+						"StatusCode": "HTTP_301",
+					},
+				},
+			},
+		})
+	})
+
+	t.Run("does not create a database unless an engine is selected", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		stack := lib.NewTapStack(app, jsii.String("NoDBTest"), &lib.TapStackProps{
+			StackProps:        &awscdk.StackProps{},
+			EnvironmentSuffix: jsii.String("nodb-test"),
+		})
+		template := assertions.Template_FromStack(stack.Stack, nil)
+
+		// ASSERT
+		assert.Nil(t, stack.Database)
+		assert.NotContains(t, stack.SecurityGroups, "db")
+		template.ResourceCountIs(jsii.String("AWS::RDS::DBCluster"), jsii.Number(0))
+	})
+
+	t.Run("creates encrypted Aurora PostgreSQL cluster in isolated subnets", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		stack := lib.NewTapStack(app, jsii.String("PostgresTest"), &lib.TapStackProps{
+			StackProps:        &awscdk.StackProps{},
+			EnvironmentSuffix: jsii.String("pg-test"),
+			DatabaseEngine:    lib.DatabaseEngineAuroraPostgres,
+		})
+		template := assertions.Template_FromStack(stack.Stack, nil)
+
+		// ASSERT - Cluster with writer and reader
+		assert.NotNil(t, stack.Database)
+		template.ResourceCountIs(jsii.String("AWS::RDS::DBCluster"), jsii.Number(1))
+		template.ResourceCountIs(jsii.String("AWS::RDS::DBInstance"), jsii.Number(2))
+		template.HasResourceProperties(jsii.String("AWS::RDS::DBCluster"), map[string]interface{}{
+			"Engine":           "aurora-postgresql",
+			"Port":             5432,
+			"StorageEncrypted": true,
+			"KmsKeyId":         assertions.Match_AnyValue(),
+		})
+
+		// ASSERT - Master credentials resolved from the application secret
+		template.ResourceCountIs(jsii.String("AWS::SecretsManager::Secret"), jsii.Number(1))
+		template.ResourceCountIs(jsii.String("AWS::SecretsManager::SecretTargetAttachment"), jsii.Number(1))
+
+		// ASSERT - Subnet group over the isolated subnets
+		template.ResourceCountIs(jsii.String("AWS::RDS::DBSubnetGroup"), jsii.Number(1))
+
+		// ASSERT - Database SG admits only EC2 and Lambda security groups
+		assert.Contains(t, stack.SecurityGroups, "db")
+		template.ResourcePropertiesCountIs(jsii.String("AWS::EC2::SecurityGroupIngress"), map[string]interface{}{
+			"FromPort": 5432,
+			"ToPort":   5432,
+		}, jsii.Number(2))
+		template.HasOutput(jsii.String("DatabaseEndpoint"), map[string]interface{}{})
+	})
+
+	t.Run("creates Aurora MySQL cluster when selected", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		stack := lib.NewTapStack(app, jsii.String("MysqlTest"), &lib.TapStackProps{
+			StackProps:        &awscdk.StackProps{},
+			EnvironmentSuffix: jsii.String("mysql-test"),
+			DatabaseEngine:    lib.DatabaseEngineAuroraMysql,
+		})
+		template := assertions.Template_FromStack(stack.Stack, nil)
+
+		// ASSERT
+		template.HasResourceProperties(jsii.String("AWS::RDS::DBCluster"), map[string]interface{}{
+			"Engine": "aurora-mysql",
+			"Port":   3306,
+		})
+		template.ResourcePropertiesCountIs(jsii.String("AWS::EC2::SecurityGroupIngress"), map[string]interface{}{
+			"FromPort": 3306,
+			"ToPort":   3306,
+		}, jsii.Number(2))
+	})
+
+	t.Run("rotates the application secret with a custom Lambda when there is no database", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		stack := lib.NewTapStack(app, jsii.String("RotationTest"), &lib.TapStackProps{
+			StackProps:         &awscdk.StackProps{},
+			EnvironmentSuffix:  jsii.String("rotation-test"),
+			SecretRotationDays: jsii.Number(7),
+		})
+		template := assertions.Template_FromStack(stack.Stack, nil)
+
+		// ASSERT - Secret encrypted with the customer-managed key
+		template.HasResourceProperties(jsii.String("AWS::SecretsManager::Secret"), map[string]interface{}{
+			"KmsKeyId": assertions.Match_AnyValue(),
+		})
+
+		// ASSERT - Rotation schedule backed by the custom rotation Lambda
+		template.ResourceCountIs(jsii.String("AWS::SecretsManager::RotationSchedule"), jsii.Number(1))
+		template.HasResourceProperties(jsii.String("AWS::SecretsManager::RotationSchedule"), map[string]interface{}{
+			"RotationLambdaARN": assertions.Match_AnyValue(),
+			"RotationRules": map[string]interface{}{
+				"AutomaticallyAfterDays": 7,
+			},
+		})
+		template.HasResourceProperties(jsii.String("AWS::Lambda::Function"), map[string]interface{}{
+			"FunctionName": "prod-rotation-test-secret-rotation",
+			"VpcConfig":    assertions.Match_AnyValue(),
+		})
+	})
+
+	t.Run("rotates the application secret with the hosted rotation Lambda when the database exists", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		stack := lib.NewTapStack(app, jsii.String("HostedRotationTest"), &lib.TapStackProps{
+			StackProps:        &awscdk.StackProps{},
+			EnvironmentSuffix: jsii.String("hosted-test"),
+			DatabaseEngine:    lib.DatabaseEngineAuroraPostgres,
+		})
+		template := assertions.Template_FromStack(stack.Stack, nil)
+		attachments := template.FindResources(jsii.String("AWS::SecretsManager::SecretTargetAttachment"), nil)
+		require.Len(t, *attachments, 1)
+		var attachment string
+		for id := range *attachments {
+			attachment = id
+		}
+
+		// ASSERT - Hosted single-user rotation on the default schedule, only
+		// once the secret is attached to the cluster
+		template.HasResourceProperties(jsii.String("AWS::SecretsManager::RotationSchedule"), map[string]interface{}{
+			"SecretId": map[string]interface{}{"Ref": attachment},
+			"HostedRotationLambda": map[string]interface{}{
+				"RotationType":       "PostgreSQLSingleUser",
+				"RotationLambdaName": "prod-hosted-test-secret-rotation",
+			},
+			"RotationRules": map[string]interface{}{
+				"AutomaticallyAfterDays": 30,
+			},
+		})
+		template.ResourcePropertiesCountIs(jsii.String("AWS::Lambda::Function"), map[string]interface{}{
+			"FunctionName": "prod-hosted-test-secret-rotation",
+		}, jsii.Number(0))
+	})
+
 	t.Run("creates bastion host with proper security group", func(t *testing.T) {
 		// ARRANGE
 		app := awscdk.NewApp(nil)
@@ -249,6 +464,15 @@ func TestTapStack(t *testing.T) {
 		})
 
 		// ASSERT - CloudFront should work with S3 origin (OAI creation may vary)
+
+		// ASSERT - WAF WebACL is associated with the distribution
+		template.HasResourceProperties(jsii.String("AWS::CloudFront::Distribution"), map[string]interface{}{
+			"DistributionConfig": map[string]interface{}{
+				"WebACLId": map[string]interface{}{
+					"Fn::GetAtt": []interface{}{logicalId(stack, stack.WAF), "Arn"},
+				},
+			},
+		})
 	})
 
 	t.Run("creates monitoring and compliance resources", func(t *testing.T) {
@@ -270,8 +494,12 @@ func TestTapStack(t *testing.T) {
 
 		// Note: Config recorder is not implemented in this stack
 
-		// ASSERT - CloudWatch alarm
-		template.ResourceCountIs(jsii.String("AWS::CloudWatch::Alarm"), jsii.Number(1))
+		// ASSERT - CloudWatch alarms (Lambda errors, secret rotation failures)
+		template.ResourceCountIs(jsii.String("AWS::CloudWatch::Alarm"), jsii.Number(2))
+		template.HasResourceProperties(jsii.String("AWS::CloudWatch::Alarm"), map[string]interface{}{
+			"MetricName":   "RotationFailed",
+			"AlarmActions": assertions.Match_AnyValue(),
+		})
 	})
 
 	t.Run("defaults environment suffix to 'dev' if not provided", func(t *testing.T) {
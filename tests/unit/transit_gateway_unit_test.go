@@ -0,0 +1,184 @@
+package lib_test
+
+import (
+	"testing"
+
+	"github.com/TuringGpt/iac-test-automations/lib"
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/assertions"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsec2"
+	"github.com/aws/jsii-runtime-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransitGateway(t *testing.T) {
+	defer jsii.Close()
+
+	t.Run("is not attached by default", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		stack := lib.NewTapStack(app, jsii.String("TapStackNoTgw"), &lib.TapStackProps{
+			EnvironmentSuffix: jsii.String("tgw"),
+		})
+		template := assertions.Template_FromStack(stack.Stack, nil)
+
+		// ASSERT
+		assert.Nil(t, stack.Network.TransitSubnets)
+		assert.Nil(t, stack.Network.TransitGatewayAttachment)
+		template.ResourceCountIs(jsii.String("AWS::EC2::TransitGatewayAttachment"), jsii.Number(0))
+		template.ResourceCountIs(jsii.String("AWS::EC2::Subnet"), jsii.Number(6))
+		assert.Empty(t, *template.FindOutputs(jsii.String("TransitGatewayAttachmentId"), nil))
+	})
+
+	t.Run("attaches from the transit tier and routes the private and isolated tiers to it", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		stack := lib.NewTapStack(app, jsii.String("TapStackTgw"), &lib.TapStackProps{
+			EnvironmentSuffix: jsii.String("tgw"),
+			TransitGateway: &lib.TransitGatewayConfig{
+				TransitGatewayId: jsii.String("tgw-0123456789abcdef0"),
+				DestinationCidrs: []string{"10.100.0.0/16", "192.168.0.0/20"},
+				ReservedCidrs:    []string{"10.1.0.0/16"},
+			},
+		})
+		template := assertions.Template_FromStack(stack.Stack, nil)
+
+		// ACT
+		attachment := logicalId(stack, stack.Network.TransitGatewayAttachment)
+		var subnetRefs []interface{}
+		for _, subnet := range *stack.Network.TransitSubnets {
+			subnetRefs = append(subnetRefs, map[string]interface{}{"Ref": logicalId(stack, subnet.Node().DefaultChild().(awscdk.CfnElement))})
+		}
+
+		// ASSERT
+		assert.Equal(t, []string{"10.0.6.0/28", "10.0.6.16/28"}, subnetCidrs(stack.Network.TransitSubnets))
+		template.HasResourceProperties(jsii.String("AWS::EC2::TransitGatewayAttachment"), map[string]interface{}{
+			"TransitGatewayId": "tgw-0123456789abcdef0",
+			"SubnetIds":        subnetRefs,
+		})
+		template.HasOutput(jsii.String("TransitGatewayAttachmentId"), map[string]interface{}{})
+
+		template.ResourcePropertiesCountIs(jsii.String("AWS::EC2::Route"), map[string]interface{}{
+			"TransitGatewayId": assertions.Match_AnyValue(),
+		}, jsii.Number(8))
+		for _, subnets := range []*[]awsec2.ISubnet{stack.PrivateSubnets, stack.IsolatedSubnets} {
+			for _, subnet := range *subnets {
+				for _, cidr := range []string{"10.100.0.0/16", "192.168.0.0/20"} {
+					template.HasResource(jsii.String("AWS::EC2::Route"), map[string]interface{}{
+						"Properties": map[string]interface{}{
+							"RouteTableId":         stack.Resolve(subnet.RouteTable().RouteTableId()),
+							"DestinationCidrBlock": cidr,
+							"TransitGatewayId":     "tgw-0123456789abcdef0",
+						},
+						// Routes to the Transit Gateway fail until the attachment exists
+						"DependsOn": assertions.Match_ArrayWith(&[]interface{}{attachment}),
+					})
+				}
+			}
+		}
+
+		// The app and data tiers admit the destinations; the public tier does not
+		for _, acl := range []string{"PrivateNacl", "IsolatedNacl"} {
+			naclId := logicalId(stack, stack.Network.Node().FindChild(jsii.String(acl)).Node().DefaultChild().(awscdk.CfnElement))
+			template.HasResourceProperties(jsii.String("AWS::EC2::NetworkAclEntry"), map[string]interface{}{
+				"NetworkAclId": map[string]interface{}{"Ref": naclId},
+				"CidrBlock":    "192.168.0.0/20",
+				"RuleNumber":   201,
+				"Egress":       false,
+				"RuleAction":   "allow",
+			})
+		}
+		template.ResourcePropertiesCountIs(jsii.String("AWS::EC2::NetworkAclEntry"), map[string]interface{}{
+			"CidrBlock": "10.100.0.0/16",
+		}, jsii.Number(3))
+	})
+
+	t.Run("rejects invalid Transit Gateway settings", func(t *testing.T) {
+		cases := []struct {
+			name    string
+			props   *lib.TapStackProps
+			message string
+		}{
+			{
+				name: "VPC CIDR overlapping a reserved range",
+				props: &lib.TapStackProps{
+					Network: &lib.NetworkConfig{VpcCidr: jsii.String("10.1.0.0/16")},
+					TransitGateway: &lib.TransitGatewayConfig{
+						TransitGatewayId: jsii.String("tgw-0123456789abcdef0"),
+						DestinationCidrs: []string{"10.100.0.0/16"},
+						ReservedCidrs:    []string{"10.0.0.0/8"},
+					},
+				},
+				message: "VpcCidr 10.1.0.0/16 overlaps TransitGateway ReservedCidrs entry 10.0.0.0/8",
+			},
+			{
+				name: "VPC CIDR overlapping a destination",
+				props: &lib.TapStackProps{TransitGateway: &lib.TransitGatewayConfig{
+					TransitGatewayId: jsii.String("tgw-0123456789abcdef0"),
+					DestinationCidrs: []string{"10.0.128.0/17"},
+				}},
+				message: "VpcCidr 10.0.0.0/16 overlaps TransitGateway DestinationCidrs entry 10.0.128.0/17",
+			},
+			{
+				name: "malformed ID",
+				props: &lib.TapStackProps{TransitGateway: &lib.TransitGatewayConfig{
+					TransitGatewayId: jsii.String("vpc-0123456789abcdef0"),
+					DestinationCidrs: []string{"10.100.0.0/16"},
+				}},
+				message: `TransitGatewayId "vpc-0123456789abcdef0" is not a Transit Gateway ID`,
+			},
+			{
+				name: "no destinations",
+				props: &lib.TapStackProps{TransitGateway: &lib.TransitGatewayConfig{
+					TransitGatewayId: jsii.String("tgw-0123456789abcdef0"),
+				}},
+				message: "TransitGateway requires between 1 and 10 DestinationCidrs",
+			},
+			{
+				name: "invalid CIDR",
+				props: &lib.TapStackProps{TransitGateway: &lib.TransitGatewayConfig{
+					TransitGatewayId: jsii.String("tgw-0123456789abcdef0"),
+					DestinationCidrs: []string{"10.100.0.0/33"},
+				}},
+				message: `DestinationCidrs entry "10.100.0.0/33" is not a valid IPv4 CIDR block`,
+			},
+			{
+				name: "existing VPC",
+				props: &lib.TapStackProps{
+					ExistingVpc: &lib.ExistingVpcConfig{VpcId: jsii.String("vpc-0123456789abcdef0")},
+					TransitGateway: &lib.TransitGatewayConfig{
+						TransitGatewayId: jsii.String("tgw-0123456789abcdef0"),
+						DestinationCidrs: []string{"10.100.0.0/16"},
+					},
+				},
+				message: "TransitGateway cannot add its subnet tier to an ExistingVpc",
+			},
+			{
+				name: "no room for the transit tier",
+				props: &lib.TapStackProps{
+					Network: &lib.NetworkConfig{
+						NetworkFirewall: jsii.Bool(true),
+						VpcCidr:         jsii.String("10.0.0.0/24"),
+						SubnetCidrMask:  jsii.Number(27),
+					},
+					TransitGateway: &lib.TransitGatewayConfig{
+						TransitGatewayId: jsii.String("tgw-0123456789abcdef0"),
+						DestinationCidrs: []string{"10.100.0.0/16"},
+					},
+				},
+				message: "has room for 0 more /28 subnets but TransitGateway needs 2",
+			},
+		}
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				// ACT
+				err := tc.props.Validate()
+
+				// ASSERT
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.message)
+			})
+		}
+	})
+}
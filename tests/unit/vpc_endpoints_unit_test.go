@@ -0,0 +1,123 @@
+package lib_test
+
+import (
+	"testing"
+
+	"github.com/TuringGpt/iac-test-automations/lib"
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/assertions"
+	"github.com/aws/jsii-runtime-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVpcEndpoints(t *testing.T) {
+	defer jsii.Close()
+
+	t.Run("are disabled by default", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		stack := lib.NewTapStack(app, jsii.String("TapStackNoEndpoints"), &lib.TapStackProps{
+			EnvironmentSuffix: jsii.String("endpoints"),
+		})
+		template := assertions.Template_FromStack(stack.Stack, nil)
+
+		// ASSERT
+		template.ResourceCountIs(jsii.String("AWS::EC2::VPCEndpoint"), jsii.Number(0))
+		assert.Nil(t, stack.Network.EndpointSecurityGroup)
+		template.HasResourceProperties(jsii.String("AWS::EC2::SecurityGroup"), map[string]interface{}{
+			"GroupDescription": "Security group for Lambda functions",
+			"SecurityGroupEgress": []interface{}{
+				map[string]interface{}{"CidrIp": "0.0.0.0/0", "IpProtocol": "-1"},
+			},
+		})
+	})
+
+	t.Run("add gateway and interface endpoints when enabled", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		stack := lib.NewTapStack(app, jsii.String("TapStackEndpoints"), &lib.TapStackProps{
+			EnvironmentSuffix: jsii.String("endpoints"),
+			Network:           &lib.NetworkConfig{VpcEndpoints: jsii.Bool(true)},
+		})
+		template := assertions.Template_FromStack(stack.Stack, nil)
+
+		// ASSERT
+		template.ResourceCountIs(jsii.String("AWS::EC2::VPCEndpoint"), jsii.Number(10))
+		gateways := template.FindResources(jsii.String("AWS::EC2::VPCEndpoint"), map[string]interface{}{
+			"Properties": map[string]interface{}{"VpcEndpointType": "Gateway"},
+		})
+		assert.Len(t, *gateways, 2)
+		interfaces := template.FindResources(jsii.String("AWS::EC2::VPCEndpoint"), map[string]interface{}{
+			"Properties": map[string]interface{}{"VpcEndpointType": "Interface", "PrivateDnsEnabled": true},
+		})
+		assert.Len(t, *interfaces, 8)
+		for _, id := range []string{"SSM", "SSMMessages", "EC2Messages", "SecretsManager", "KMS", "CloudWatchLogs", "STS", "XRay"} {
+			assert.Contains(t, stack.Network.InterfaceEndpoints, id)
+		}
+		require.NotNil(t, stack.Network.EndpointSecurityGroup)
+		template.HasResourceProperties(jsii.String("AWS::EC2::SecurityGroup"), map[string]interface{}{
+			"GroupDescription": "Security group for interface VPC endpoints",
+			"SecurityGroupIngress": []interface{}{
+				assertions.Match_ObjectLike(&map[string]interface{}{"FromPort": 443, "ToPort": 443}),
+			},
+		})
+	})
+
+	t.Run("admit HTTPS over IPv6 in dual-stack mode", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		stack := lib.NewTapStack(app, jsii.String("TapStackEndpointsIpv6"), &lib.TapStackProps{
+			EnvironmentSuffix: jsii.String("endpoints"),
+			Network:           &lib.NetworkConfig{VpcEndpoints: jsii.Bool(true), DualStack: jsii.Bool(true)},
+		})
+		template := assertions.Template_FromStack(stack.Stack, nil)
+
+		// ASSERT
+		template.HasResourceProperties(jsii.String("AWS::EC2::SecurityGroup"), map[string]interface{}{
+			"GroupDescription": "Security group for interface VPC endpoints",
+			"SecurityGroupIngress": []interface{}{
+				assertions.Match_ObjectLike(&map[string]interface{}{"CidrIp": assertions.Match_AnyValue(), "FromPort": 443, "ToPort": 443}),
+				assertions.Match_ObjectLike(&map[string]interface{}{
+					"CidrIpv6": stack.Resolve(stack.Network.Ipv6CidrBlock),
+					"FromPort": 443,
+					"ToPort":   443,
+				}),
+			},
+		})
+		template.HasResource(jsii.String("AWS::EC2::SecurityGroup"), map[string]interface{}{
+			"Properties": map[string]interface{}{"GroupDescription": "Security group for interface VPC endpoints"},
+			"DependsOn":  assertions.Match_ArrayWith(&[]interface{}{assertions.Match_StringLikeRegexp(jsii.String("Ipv6CidrBlock"))}),
+		})
+	})
+
+	t.Run("limit Lambda egress to the endpoints", func(t *testing.T) {
+		// ARRANGE
+		app := awscdk.NewApp(nil)
+		stack := lib.NewTapStack(app, jsii.String("TapStackEndpointEgress"), &lib.TapStackProps{
+			EnvironmentSuffix: jsii.String("endpoints"),
+			Network:           &lib.NetworkConfig{VpcEndpoints: jsii.Bool(true)},
+		})
+		template := assertions.Template_FromStack(stack.Stack, nil)
+
+		// ASSERT - the any-destination rule is gone, only endpoint rules remain
+		lambdaSG := template.FindResources(jsii.String("AWS::EC2::SecurityGroup"), map[string]interface{}{
+			"Properties": map[string]interface{}{
+				"GroupDescription":    "Security group for Lambda functions",
+				"SecurityGroupEgress": assertions.Match_Absent(),
+			},
+		})
+		assert.Len(t, *lambdaSG, 1)
+		template.HasResourceProperties(jsii.String("AWS::EC2::SecurityGroupEgress"), map[string]interface{}{
+			"FromPort":                   443,
+			"DestinationSecurityGroupId": assertions.Match_AnyValue(),
+			"Description":                "HTTPS to the interface VPC endpoints",
+		})
+		template.HasResourceProperties(jsii.String("AWS::EC2::SecurityGroupEgress"), map[string]interface{}{
+			"FromPort":                443,
+			"DestinationPrefixListId": assertions.Match_AnyValue(),
+			"Description":             "HTTPS to S3 through the gateway endpoint",
+		})
+		require.NotNil(t, stack.Network.S3PrefixListId)
+	})
+}